@@ -0,0 +1,203 @@
+package logs
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrWriterClosed is returned when writing to an [AsyncWriter] that has been
+// closed.
+var ErrWriterClosed = errors.New("writer is closed")
+
+// AsyncWriter is an io.Writer that queues each write and passes it to another
+// io.Writer from a background goroutine. Use it with [WithOutput] so that
+// printing never waits on a slow output.
+type AsyncWriter struct {
+	w       io.Writer
+	queue   chan asyncWrite
+	block   bool
+	dropped atomic.Uint64
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	errMu sync.Mutex
+	err   error
+}
+
+// asyncWrite is a queued write, or a request to be notified once all earlier
+// writes have completed. A write made using [AsyncWriter.WriteLevel] keeps its
+// level, and one made using [AsyncWriter.WriteMetadata] keeps its metadata.
+type asyncWrite struct {
+	data    []byte
+	level   Level
+	leveled bool
+	meta    *Metadata
+	flushed chan struct{}
+}
+
+type asyncOptions struct {
+	size  int
+	block bool
+}
+
+// AsyncOption is a configuration option for an [AsyncWriter].
+type AsyncOption func(*asyncOptions)
+
+// WithQueueSize sets the number of writes that an [AsyncWriter] can hold while
+// they wait to be passed on. The default is 1024.
+func WithQueueSize(n int) AsyncOption {
+	return func(o *asyncOptions) {
+		o.size = n
+	}
+}
+
+// WithBlocking configures an [AsyncWriter] to wait for space in its queue when
+// the queue is full, rather than dropping the write.
+func WithBlocking() AsyncOption {
+	return func(o *asyncOptions) {
+		o.block = true
+	}
+}
+
+// NewAsyncWriter creates an [AsyncWriter] that passes writes on to w. By
+// default, a write is dropped and counted if the queue is full. Call
+// [AsyncWriter.Close] to stop the background goroutine once all writes are
+// complete.
+func NewAsyncWriter(w io.Writer, opts ...AsyncOption) *AsyncWriter {
+	o := asyncOptions{size: 1024}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	a := &AsyncWriter{
+		w:     w,
+		queue: make(chan asyncWrite, o.size),
+		block: o.block,
+		done:  make(chan struct{}),
+	}
+
+	go a.run()
+	return a
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	for item := range a.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+
+		var err error
+		switch {
+		case item.meta != nil:
+			_, err = writeMeta(a.w, *item.meta, item.data)
+		case item.leveled:
+			_, err = writeLevel(a.w, item.level, item.data)
+		default:
+			_, err = a.w.Write(item.data)
+		}
+
+		if err != nil {
+			a.errMu.Lock()
+			if a.err == nil {
+				a.err = err
+			}
+			a.errMu.Unlock()
+		}
+	}
+}
+
+// Write queues a copy of p to be passed on. It returns [ErrWriterClosed] if the
+// writer has been closed. A write that is dropped because the queue is full is
+// not reported as an error, but is counted by [AsyncWriter.Dropped].
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	return a.enqueue(asyncWrite{data: append([]byte(nil), p...)})
+}
+
+// WriteLevel queues a copy of p as [AsyncWriter.Write] does, and passes the
+// level on with it if the wrapped writer is a [LevelWriter].
+func (a *AsyncWriter) WriteLevel(level Level, p []byte) (int, error) {
+	return a.enqueue(asyncWrite{data: append([]byte(nil), p...), level: level, leveled: true})
+}
+
+// WriteMetadata queues a copy of p as [AsyncWriter.Write] does, and passes the
+// metadata on with it if the wrapped writer is a [MetadataWriter], or the
+// level if it is a [LevelWriter].
+func (a *AsyncWriter) WriteMetadata(meta Metadata, p []byte) (int, error) {
+	return a.enqueue(asyncWrite{data: append([]byte(nil), p...), meta: &meta})
+}
+
+func (a *AsyncWriter) enqueue(item asyncWrite) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+
+	if a.block {
+		a.queue <- item
+		return len(item.data), nil
+	}
+
+	select {
+	case a.queue <- item:
+	default:
+		a.dropped.Add(1)
+	}
+
+	return len(item.data), nil
+}
+
+// Flush waits until all writes queued before the call have been passed on. It
+// returns the first error encountered while passing on writes since the
+// previous call to Flush.
+func (a *AsyncWriter) Flush() error {
+	a.mu.RLock()
+	if !a.closed {
+		flushed := make(chan struct{})
+		a.queue <- asyncWrite{flushed: flushed}
+		a.mu.RUnlock()
+		<-flushed
+	} else {
+		a.mu.RUnlock()
+	}
+
+	return a.takeErr()
+}
+
+// Close stops accepting writes, waits until all queued writes have been passed
+// on, and stops the background goroutine. It returns the first error
+// encountered while passing on writes since the last call to Flush.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+	return a.takeErr()
+}
+
+// Dropped returns the number of writes that were dropped because the queue was
+// full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+func (a *AsyncWriter) takeErr() error {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+
+	err := a.err
+	a.err = nil
+	return err
+}
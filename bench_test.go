@@ -0,0 +1,111 @@
+package logs_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rclark/logs"
+)
+
+func BenchmarkPrint_freeformFlat(b *testing.B) {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "method", "GET", "path", "/users/42", "status", 200, "cached", true, "ratio", 0.25)
+
+	out := logs.WithOutput(io.Discard)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logs.Print(ctx, out)
+	}
+}
+
+func BenchmarkPrint_freeformNested(b *testing.B) {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "http.method", "GET", "http.path", "/users/42", "http.status", 200, "user.id", 42, "user.roles", []string{"admin", "dev"})
+
+	out := logs.WithOutput(io.Discard)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logs.Print(ctx, out)
+	}
+}
+
+func BenchmarkPrint_struct(b *testing.B) {
+	logger := logs.NewLogger(logs.NewExampleLog)
+	ctx := logger.AddEntry(context.Background())
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+		e.Count = 42
+		e.Messages = []string{"hello", "world"}
+	})
+
+	out := logs.WithOutput(io.Discard)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Print(ctx, out)
+	}
+}
+
+// TestPrint_freeformEncoding checks that freeform log entries, which are
+// encoded directly into a buffer, are printed exactly as the JSONEncoder would
+// print them. Setting a maximum line size sends the log entry through the
+// encoder instead.
+func TestPrint_freeformEncoding(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx,
+		"string", "plain",
+		"escaped", "<a href=\"x\">&\n\t é</a>",
+		"invalid", "\xff",
+		"bool", false,
+		"nil", nil,
+		"int", -42,
+		"int8", int8(-8),
+		"uint64", uint64(math.MaxUint64),
+		"float", 0.1,
+		"small", 1e-7,
+		"large", 1e21,
+		"whole", 3.0,
+		"float32", float32(3.14),
+		"tiny32", float32(1e-9),
+		"strings", []string{"a", "<b>"},
+		"nilStrings", []string(nil),
+		"any", []any{1, "two", map[string]any{"three": 3}},
+		"map", map[string]any{"z": 1, "a": 2},
+		"nested.key", "value",
+		"struct", point{1, 2},
+		"time", time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		"bytes", []byte("hi"),
+		"unicode.ключ", "значение",
+	)
+
+	for _, opts := range [][]logs.PrintOption{
+		nil,
+		{logs.WithTimeFormat(time.RFC3339Nano)},
+		{logs.WithTimeFormat(logs.TimeUnixMilli), logs.WithMetadataKeys("level", "ts")},
+		{logs.WithRunID()},
+	} {
+		for _, ctx := range []context.Context{ctx, logs.AddEntry(context.Background())} {
+			var fast, slow bytes.Buffer
+			now := logs.WithCurrentTime(time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC))
+			logs.Print(ctx, append([]logs.PrintOption{now, logs.WithOutput(&fast)}, opts...)...)
+			logs.Print(ctx, append([]logs.PrintOption{now, logs.WithOutput(&slow), logs.WithMaxLineBytes(1 << 20)}, opts...)...)
+
+			if fast.String() != slow.String() {
+				t.Errorf("expected\n%s\ngot\n%s", slow.String(), fast.String())
+			}
+		}
+	}
+}
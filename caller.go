@@ -0,0 +1,121 @@
+package logs
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// pkgPrefix is the prefix of the names of this package's functions, which are
+// skipped when finding the call site of a log entry.
+const pkgPrefix = "github.com/rclark/logs."
+
+// maxCallerDepth is the number of stack frames captured for a call site. It
+// needs to be large enough to reach past this package's own frames.
+const maxCallerDepth = 8
+
+// WithCaller configures the log entry to record the call site that created it,
+// or that most recently set its level using a function like [Error] or
+// [SetLevel]. The call site is printed as "file.go:42" under the "@caller" key.
+// The file is given relative to its directory, such as "api/users.go".
+//
+// The skip argument is the number of additional stack frames to skip, which is
+// useful when the log entry is leveled by a helper function: a skip of 1
+// records the helper's caller rather than the helper. Capturing a call site is
+// cheap, as it is only resolved to a file and line when the log entry is
+// printed.
+func WithCaller(skip int) Option {
+	return func(o *option) {
+		o.caller = true
+		o.callerSkip = skip
+	}
+}
+
+// WithCallerFunction configures the log entry to record its call site, as
+// described by [WithCaller], and also to print the name of the calling
+// function under the "@function" key.
+func WithCallerFunction(skip int) Option {
+	return func(o *option) {
+		o.caller = true
+		o.callerSkip = skip
+		o.callerFunc = true
+	}
+}
+
+// callSite is the call site of a log entry, captured as program counters.
+type callSite struct {
+	skip     int
+	function bool
+	pcs      []uintptr
+	n        int
+}
+
+// newCallSite prepares to capture call sites for a log entry. The function
+// will return nil if the log entry should not record its call site.
+func newCallSite(o option) *callSite {
+	if !o.caller {
+		return nil
+	}
+
+	return &callSite{
+		skip:     o.callerSkip,
+		function: o.callerFunc,
+		pcs:      make([]uintptr, maxCallerDepth+o.callerSkip),
+	}
+}
+
+// capture records the current call site.
+func (c *callSite) capture() {
+	if c != nil {
+		// Skip runtime.Callers and capture itself.
+		c.n = runtime.Callers(2, c.pcs)
+	}
+}
+
+// clone copies the call site for a detached log entry.
+func (c *callSite) clone() *callSite {
+	if c == nil {
+		return nil
+	}
+
+	clone := *c
+	clone.pcs = append([]uintptr(nil), c.pcs...)
+	return &clone
+}
+
+// fields resolves the call site to the meta fields that describe it, skipping
+// the frames of this package and then the configured number of frames.
+func (c *callSite) fields() []MetaField {
+	if c == nil || c.n == 0 {
+		return nil
+	}
+
+	skip := c.skip
+	frames := runtime.CallersFrames(c.pcs[:c.n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkgPrefix) {
+			if skip == 0 {
+				return c.describe(frame)
+			}
+			skip--
+		}
+
+		if !more {
+			return nil
+		}
+	}
+}
+
+func (c *callSite) describe(frame runtime.Frame) []MetaField {
+	dir, file := filepath.Split(frame.File)
+	location := filepath.Base(dir) + "/" + file + ":" + strconv.Itoa(frame.Line)
+
+	fields := []MetaField{{"@caller", location}}
+	if c.function {
+		fields = append(fields, MetaField{"@function", frame.Function})
+	}
+
+	return fields
+}
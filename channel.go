@@ -0,0 +1,52 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// ChannelWriter is an io.Writer that decodes each log entry written to it and
+// sends it on a channel. Use it with [WithOutput] to process log entries within
+// the same application.
+type ChannelWriter struct {
+	ch      chan<- map[string]any
+	dropped atomic.Uint64
+}
+
+// NewChannelWriter creates a [ChannelWriter] that sends decoded log entries on
+// the channel. Sending never blocks: if the channel is full, the log entry is
+// dropped and counted.
+func NewChannelWriter(ch chan<- map[string]any) *ChannelWriter {
+	return &ChannelWriter{ch: ch}
+}
+
+// Write decodes each line of p as a JSON object and sends it on the channel.
+// It returns an error if any line is not a JSON object.
+func (w *ChannelWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var m map[string]any
+		if err := json.Unmarshal(line, &m); err != nil {
+			return 0, fmt.Errorf("failed to decode log entry: %w", err)
+		}
+
+		select {
+		case w.ch <- m:
+		default:
+			w.dropped.Add(1)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Dropped returns the number of log entries that were dropped because the
+// channel was full.
+func (w *ChannelWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
@@ -0,0 +1,68 @@
+package logs
+
+import (
+	"net/http"
+	"time"
+)
+
+// HttpClientData is the data structure that [Transport] appends to the
+// "@http_client" key of a log entry for each outbound request.
+type HttpClientData struct {
+	Method   string        `json:"method"`
+	URL      string        `json:"url"`
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// transport is an http.RoundTripper that records outbound requests.
+type transport struct {
+	next http.RoundTripper
+	opt  option
+}
+
+// Transport wraps an http.RoundTripper so that each outbound request is
+// recorded in the freeform log entry in the request's context. The method, URL,
+// status, and duration of each request are appended to the "@http_client" key,
+// so that a single log entry shows all of the downstream calls that a handler
+// made. The URL is recorded without its query string, which often carries
+// credentials such as API keys. Requests whose context has no freeform log
+// entry are passed on without being recorded. If next is nil,
+// http.DefaultTransport is used.
+//
+// The transport may be used by concurrent requests that share a context.
+func Transport(next http.RoundTripper, opts ...MiddlewareOption) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &transport{next: next, opt: applyOptions(opts...)}
+}
+
+// RoundTrip passes the request on and records it.
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	if getEntry[FreeformEntry](ctx) == nil {
+		return t.next.RoundTrip(r)
+	}
+
+	start := t.opt.timer.Now()
+	resp, err := t.next.RoundTrip(r)
+
+	u := *r.URL
+	u.RawQuery, u.ForceQuery = "", false
+
+	data := HttpClientData{
+		Method:   r.Method,
+		URL:      u.Redacted(),
+		Duration: t.opt.timer.Since(start),
+	}
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		data.Status = resp.StatusCode
+	}
+
+	appendValues(ctx, "@http_client", Promote, data)
+	return resp, err
+}
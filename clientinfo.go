@@ -0,0 +1,119 @@
+package logs
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// WithClientInfo configures the middleware to write the client's address,
+// user agent and referer into each log entry. The remote address is the
+// address of the connection, and the client IP is the address of the client
+// that made the request, which differs when the request passes through proxies
+// configured using [WithTrustedProxies]. This option will have no effect
+// unless [Middleware] is operating on a [FreeformEntry], or a custom type
+// receives [HttpData].
+func WithClientInfo() MiddlewareOption {
+	return func(o *option) {
+		o.clientInfo = true
+	}
+}
+
+// WithTrustedProxies configures the proxies, given as IP addresses or CIDR
+// ranges such as "10.0.0.0/8", whose Forwarded and X-Forwarded-For headers are
+// believed when finding the client IP for [WithClientInfo]. The client IP is
+// the nearest address in the chain of forwarding headers that is not a trusted
+// proxy. Without trusted proxies, the headers are ignored, since any client can
+// set them, and the client IP is the remote address. Entries that are not
+// valid addresses or ranges are ignored.
+func WithTrustedProxies(proxies ...string) MiddlewareOption {
+	return func(o *option) {
+		for _, p := range proxies {
+			if prefix, err := netip.ParsePrefix(p); err == nil {
+				o.trustedProxies = append(o.trustedProxies, prefix.Masked())
+			} else if addr, err := netip.ParseAddr(p); err == nil {
+				o.trustedProxies = append(o.trustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+			}
+		}
+	}
+}
+
+// clientIP finds the address of the client that made the request.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	remote, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return ""
+	}
+
+	if !isTrusted(remote, trusted) {
+		return remote.String()
+	}
+
+	chain := forwardedFor(r.Header)
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(chain[i])
+		if !ok {
+			break
+		}
+		if !isTrusted(addr, trusted) {
+			return addr.String()
+		}
+		remote = addr
+	}
+
+	return remote.String()
+}
+
+// forwardedFor returns the addresses that a request was forwarded for, from
+// the client to the nearest proxy, using the Forwarded header if it is present
+// and the X-Forwarded-For header otherwise.
+func forwardedFor(h http.Header) []string {
+	var chain []string
+	for _, v := range h.Values("Forwarded") {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(k, "for") {
+					chain = append(chain, strings.Trim(v, `"`))
+				}
+			}
+		}
+	}
+	if len(chain) > 0 {
+		return chain
+	}
+
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(v, ",") {
+			chain = append(chain, strings.TrimSpace(addr))
+		}
+	}
+
+	return chain
+}
+
+// parseAddr parses an IP address that may have a port, and may be enclosed in
+// brackets.
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// ConnData describes the connection that a request arrived on. The middleware
+// writes it into [HttpData] when the [WithConnectionInfo] option is used.
+type ConnData struct {
+	Protocol    string `json:"protocol"`
+	H2C         bool   `json:"h2c,omitempty"`
+	TLSVersion  string `json:"tls_version,omitempty"`
+	CipherSuite string `json:"cipher_suite,omitempty"`
+	ServerName  string `json:"server_name,omitempty"`
+	ALPN        string `json:"alpn,omitempty"`
+	Resumed     bool   `json:"resumed,omitempty"`
+}
+
+// WithConnectionInfo configures the middleware to write details of the
+// connection that each request arrived on into the log entry, under the
+// "conn" key of the HTTP data. They include the HTTP protocol version, and for
+// TLS connections the TLS version, cipher suite, server name (SNI) and
+// negotiated application protocol (ALPN). An HTTP/2 request without TLS is
+// marked as h2c. This is useful for debugging protocol negotiation. This
+// option will have no effect unless [Middleware] is operating on a
+// [FreeformEntry], or a custom type receives [HttpData].
+func WithConnectionInfo() MiddlewareOption {
+	return func(o *option) {
+		o.connInfo = true
+	}
+}
+
+// connData describes the connection that the request arrived on.
+func connData(r *http.Request) *ConnData {
+	data := &ConnData{
+		Protocol: r.Proto,
+		H2C:      r.ProtoMajor == 2 && r.TLS == nil,
+	}
+
+	if r.TLS != nil {
+		data.TLSVersion = tls.VersionName(r.TLS.Version)
+		data.CipherSuite = tls.CipherSuiteName(r.TLS.CipherSuite)
+		data.ServerName = r.TLS.ServerName
+		data.ALPN = r.TLS.NegotiatedProtocol
+		data.Resumed = r.TLS.DidResume
+	}
+
+	return data
+}
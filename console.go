@@ -0,0 +1,106 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ConsoleEncoder formats log entries as human-readable lines, with the
+// timestamp and level followed by the log entry's fields as sorted key=value
+// pairs. Nested fields are flattened using dot notation. If Color is true, the
+// level is colorized using ANSI escape codes.
+type ConsoleEncoder struct {
+	Color bool
+}
+
+// Encode formats the log entry as a human-readable line.
+func (c ConsoleEncoder) Encode(meta Metadata, entry any) ([]byte, error) {
+	data, err := marshalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(consoleValue(meta.FormatTime()))
+	buf.WriteByte(' ')
+	if c.Color {
+		buf.WriteString(levelColor(meta.Level))
+		buf.WriteString(meta.Level.String())
+		buf.WriteString("\x1b[0m")
+	} else {
+		buf.WriteString(meta.Level.String())
+	}
+
+	fields := make(map[string]any)
+	for _, f := range meta.Fields {
+		fields[f.Key] = f.Value
+	}
+
+	if m, ok := toMap(data); ok {
+		flatten("", m, fields)
+		for _, k := range sortedKeys(fields) {
+			writePair(&buf, k, fields[k])
+		}
+	} else {
+		for _, k := range sortedKeys(fields) {
+			writePair(&buf, k, fields[k])
+		}
+		buf.WriteByte(' ')
+		buf.Write(bytes.TrimSpace(data))
+	}
+
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// levelColor chooses the ANSI escape code used to colorize a level.
+func levelColor(level Level) string {
+	switch {
+	case level >= FATAL:
+		return "\x1b[35m"
+	case level >= ERROR:
+		return "\x1b[31m"
+	case level >= WARN:
+		return "\x1b[33m"
+	case level >= INFO:
+		return "\x1b[36m"
+	default:
+		return "\x1b[90m"
+	}
+}
+
+// flatten copies nested maps into a single map with dot-notation keys.
+func flatten(prefix string, m map[string]any, into map[string]any) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+			flatten(key, nested, into)
+			continue
+		}
+
+		into[key] = v
+	}
+}
+
+// consoleValue formats a single value for a human-readable line. Strings are
+// quoted only when necessary.
+func consoleValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		if val == "" || strings.ContainsAny(val, " =\"\t\n") {
+			return strconv.Quote(val)
+		}
+		return val
+	case json.Number:
+		return val.String()
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
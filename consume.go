@@ -0,0 +1,118 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MessageData is the data structure that [ConsumeMiddleware] writes into log
+// entries under the "@message" key. It describes the processing of a single
+// message from a queue or topic.
+type MessageData struct {
+	ID        string        `json:"id,omitempty"`
+	Queue     string        `json:"queue,omitempty"`
+	Topic     string        `json:"topic,omitempty"`
+	Partition *int32        `json:"partition,omitempty"`
+	Offset    *int64        `json:"offset,omitempty"`
+	Attempt   int           `json:"attempt,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Outcome   string        `json:"outcome"`
+	Error     string        `json:"error,omitempty"`
+	Panic     *PanicData    `json:"panic,omitempty"`
+}
+
+// The outcomes of processing a message, as recorded in [MessageData].
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomePanic   = "panic"
+)
+
+// SQSMessage describes a message received from an SQS queue, for use in the
+// function passed to [ConsumeMiddleware]. The queue may be given as a queue
+// URL or as the ARN found in the event source of a Lambda event, and is
+// recorded by name. The receive count is the message's
+// ApproximateReceiveCount attribute, and is recorded as the attempt number.
+func SQSMessage(queue, messageID string, receiveCount int) MessageData {
+	if i := strings.LastIndexAny(queue, ":/"); i >= 0 {
+		queue = queue[i+1:]
+	}
+
+	return MessageData{ID: messageID, Queue: queue, Attempt: receiveCount}
+}
+
+// KafkaMessage describes a message consumed from a Kafka topic, for use in the
+// function passed to [ConsumeMiddleware]. A Kafka message is identified by its
+// partition and offset.
+func KafkaMessage(topic string, partition int32, offset int64) MessageData {
+	return MessageData{Topic: topic, Partition: &partition, Offset: &offset}
+}
+
+// ConsumeMiddleware wraps a message handler so that each message is processed
+// with a new freeform log entry in its context, which is printed once the
+// handler returns. It brings the single log line per request that the
+// [Middleware] gives HTTP handlers to consumers of SQS queues, Kafka topics and
+// similar message streams.
+//
+// The describe function identifies a message, and is usually written using
+// [SQSMessage] or [KafkaMessage] to suit the client library in use:
+//
+//	describe := func(m *sarama.ConsumerMessage) logs.MessageData {
+//		return logs.KafkaMessage(m.Topic, m.Partition, m.Offset)
+//	}
+//	consume := logs.ConsumeMiddleware(handle, describe)
+//
+// The message's description, along with the processing duration and outcome,
+// is written under the "@message" key. If the handler returns an error, its
+// message is written there too, the log entry's level is set to ERROR, and the
+// error is returned.
+//
+// The options configure the log entry and how it is printed, as they do for
+// the [Middleware]. Panics are handled as they are by [Job].
+func ConsumeMiddleware[M any](handler func(context.Context, M) error, describe func(M) MessageData, opts ...MiddlewareOption) func(context.Context, M) error {
+	opt := applyOptions(opts...)
+
+	var options = func(o *option) {
+		*o = opt
+	}
+
+	return func(ctx context.Context, msg M) error {
+		ctx = AddEntry(ctx, options)
+
+		data := describe(msg)
+		data.Outcome = OutcomeSuccess
+
+		start := opt.timer.Now()
+		v, p, err := call(ctx, func(ctx context.Context) error {
+			return handler(ctx, msg)
+		})
+		data.Duration = opt.timer.Since(start)
+
+		if err != nil {
+			data.Outcome = OutcomeError
+			data.Error = err.Error()
+			Error(ctx)
+		}
+
+		if p != nil {
+			data.Outcome = OutcomePanic
+			data.Panic = p
+			Error(ctx)
+		}
+
+		Add(ctx, "@message", data)
+		Print(ctx, options)
+		releaseEntry[FreeformEntry](ctx)
+
+		if p != nil {
+			if !opt.recovery {
+				panic(v)
+			}
+			err = fmt.Errorf("message handler panicked: %v", v)
+		}
+
+		return err
+	}
+}
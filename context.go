@@ -0,0 +1,52 @@
+package logs
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithContextFields configures printing to add the meta fields that fn
+// provides for the context being printed to the log entry, which lets
+// integrations such as tracing libraries add fields taken from the context. fn
+// may return nil when the context holds nothing to add.
+func WithContextFields(fn func(context.Context) []MetaField) PrintOption {
+	return func(o *option) {
+		o.contextFields = append(o.contextFields, fn)
+	}
+}
+
+// ContextFields configures the middleware to add the meta fields that fn
+// provides for each request's context, as described by [WithContextFields].
+func ContextFields(fn func(context.Context) []MetaField) MiddlewareOption {
+	return MiddlewareOption(WithContextFields(fn))
+}
+
+// WithRequestContext configures the middleware to replace each request's
+// context with the one that fn derives from the request, before the log entry
+// is added to it. This lets integrations such as tracing libraries read values
+// from the request's headers into its context, where the downstream handler
+// and [WithContextFields] can find them.
+func WithRequestContext(fn func(*http.Request) context.Context) MiddlewareOption {
+	return func(o *option) {
+		o.requestContext = append(o.requestContext, fn)
+	}
+}
+
+// withRequestContext derives the request's context using the functions that
+// the middleware is configured with.
+func withRequestContext(r *http.Request, opt option) *http.Request {
+	for _, fn := range opt.requestContext {
+		r = r.WithContext(fn(r))
+	}
+	return r
+}
+
+// contextFields provides the meta fields that the configured functions add for
+// the context.
+func contextFields(ctx context.Context, fns []func(context.Context) []MetaField) []MetaField {
+	var fields []MetaField
+	for _, fn := range fns {
+		fields = append(fields, fn(ctx)...)
+	}
+	return fields
+}
@@ -0,0 +1,153 @@
+package logs
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DatadogEncoder formats log entries as JSON objects that Datadog's log
+// pipelines parse without any custom configuration. The level is written as
+// "status" and the time as "timestamp". The HTTP data that the middleware
+// writes under the "@http" key is mapped to Datadog's standard "http.*",
+// "network.*" and "duration" attributes; any of its fields that have no
+// equivalent remain under "@http". The trace fields added by the otellog
+// package's WithTrace option, or the trace context read by [WithTraceHeaders],
+// are written as "dd.trace_id" and "dd.span_id" so that log entries are
+// connected to their traces.
+type DatadogEncoder struct {
+	// Service, Env and Version are written as "dd.service", "dd.env" and
+	// "dd.version" for Datadog's unified service tagging, unless they are
+	// empty.
+	Service string
+	Env     string
+	Version string
+}
+
+// DatadogFormat configures printing to format log entries for Datadog using a
+// [DatadogEncoder]. The service, environment and version are read from the
+// DD_SERVICE, DD_ENV and DD_VERSION environment variables. To use this format
+// with the middleware, provide the [Encoding] option instead.
+func DatadogFormat() PrintOption {
+	service, _ := lookupEnv("DD_SERVICE")
+	env, _ := lookupEnv("DD_ENV")
+	version, _ := lookupEnv("DD_VERSION")
+
+	return func(o *option) {
+		o.encoder = DatadogEncoder{Service: service, Env: env, Version: version}
+	}
+}
+
+// Encode formats the log entry as a JSON object for Datadog.
+func (d DatadogEncoder) Encode(meta Metadata, entry any) ([]byte, error) {
+	data, err := marshalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := toMap(data)
+	if !ok {
+		m = map[string]any{"message": json.RawMessage(data)}
+	}
+
+	var trace *TraceData
+	for _, f := range meta.Fields {
+		switch f.Key {
+		case "trace_id":
+			setPath(m, "dd.trace_id", datadogID(f.Value))
+		case "span_id":
+			setPath(m, "dd.span_id", datadogID(f.Value))
+		case "trace_flags":
+		case "@trace":
+			trace, _ = f.Value.(*TraceData)
+		default:
+			m[f.Key] = f.Value
+		}
+	}
+
+	// The trace context of an OpenTelemetry span takes precedence over the
+	// trace context read from request headers.
+	if dd, _ := m["dd"].(map[string]any); trace != nil && dd["trace_id"] == nil {
+		setPath(m, "dd.trace_id", datadogID(trace.TraceID))
+		setPath(m, "dd.span_id", datadogID(trace.SpanID))
+	}
+
+	if http, ok := m["@http"].(map[string]any); ok {
+		datadogHttp(m, http, meta.DurationFormat)
+		if len(http) == 0 {
+			delete(m, "@http")
+		}
+	}
+
+	for name, value := range map[string]string{"dd.service": d.Service, "dd.env": d.Env, "dd.version": d.Version} {
+		if value != "" {
+			setPath(m, name, value)
+		}
+	}
+
+	meta.TimeFormat = time.RFC3339Nano
+	m["status"] = strings.ToLower(meta.Level.String())
+	m["timestamp"] = meta.FormatTime()
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+// datadogHttp moves the fields of the middleware's HTTP data that have Datadog
+// equivalents into the log entry. The duration is read in the style that it
+// was written, and moved as a number of nanoseconds.
+func datadogHttp(m, http map[string]any, durations DurationStyle) {
+	if path, ok := http["path"]; ok {
+		setPath(m, "http.url", path)
+	}
+
+	moveFields(m, http, map[string]string{
+		"method":         "http.method",
+		"path":           "http.url_details.path",
+		"query":          "http.url_details.queryString",
+		"route":          "http.route",
+		"request_id":     "http.request_id",
+		"client_ip":      "network.client.ip",
+		"user_agent":     "http.useragent",
+		"referer":        "http.referer",
+		"status":         "http.status_code",
+		"body_bytes":     "network.bytes_read",
+		"response_bytes": "network.bytes_written",
+	})
+
+	if d, ok := durations.parse(http["duration"]); ok {
+		m["duration"] = int64(d)
+		delete(http, "duration")
+	}
+
+	if headers, ok := http["headers"].(map[string]any); ok {
+		if ua, ok := headers["User-Agent"]; ok {
+			setPath(m, "http.useragent", ua)
+		}
+		if ref, ok := headers["Referer"]; ok {
+			setPath(m, "http.referer", ref)
+		}
+	}
+}
+
+// datadogID converts a hexadecimal trace or span ID to the decimal form that
+// Datadog uses. A 128-bit trace ID is reduced to its lower 64 bits, as
+// Datadog's tracers do when correlating logs.
+func datadogID(id any) string {
+	s, _ := id.(string)
+	if len(s) > 16 {
+		s = s[len(s)-16:]
+	}
+
+	n, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return s
+	}
+
+	return strconv.FormatUint(n, 10)
+}
@@ -0,0 +1,47 @@
+package logs
+
+// WithDefaults pre-populates the log entry when it is created by applying the
+// functions to it, after the [EntryMaker]. Use this to add static metadata,
+// such as a service name, to every log entry without repeating it in every
+// handler. Functions whose type does not match the log entry's type are
+// ignored, and a diagnostic message is written to os.Stderr. To bind functions
+// to every log entry that a [Logger] creates, use [Logger.WithBound].
+func WithDefaults[T any](fns ...func(*T)) Option {
+	return func(o *option) {
+		for _, fn := range fns {
+			o.defaults = append(o.defaults, fn)
+		}
+	}
+}
+
+// WithFields pre-populates a freeform log entry with key-value pairs when it is
+// created, as if they were added using [Add]. It is equivalent to using
+// [WithDefaults] with a function that adds the key-value pairs.
+func WithFields(args ...any) Option {
+	return WithDefaults(func(e *FreeformEntry) {
+		toKeyValues(args...).adjust(*e)
+	})
+}
+
+// Defaults pre-populates the log entries produced by the middleware, as
+// described by [WithDefaults].
+func Defaults[T any](fns ...func(*T)) MiddlewareOption {
+	return MiddlewareOption(WithDefaults(fns...))
+}
+
+// Fields pre-populates the freeform log entries produced by the middleware
+// with key-value pairs, as described by [WithFields].
+func Fields(args ...any) MiddlewareOption {
+	return MiddlewareOption(WithFields(args...))
+}
+
+// applyDefaults applies the functions that match the log entry's type.
+func applyDefaults[T any](data *T, o option) {
+	for _, d := range o.defaults {
+		if fn, ok := d.(func(*T)); ok {
+			fn(data)
+		} else {
+			mismatched[T]("default", d)
+		}
+	}
+}
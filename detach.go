@@ -0,0 +1,134 @@
+package logs
+
+import (
+	"context"
+	"reflect"
+)
+
+// Detach returns a context for work that outlives the current one, such as a
+// goroutine that a handler starts before it returns. The returned context is
+// never canceled, and carries a copy of the log entry in ctx along with any
+// [Logger] that was placed in ctx using [Logger.Set]. The copy has the same
+// fields and level as the original at the time of the call, but is changed and
+// printed independently of it, so the background work can print its own log
+// entry without racing with the printing of the original. If ctx has no log
+// entry, the returned context has none either.
+//
+// Log entries are copied deeply by following their maps, slices, pointers and
+// exported struct fields. Log entries that refer to themselves cannot be
+// copied.
+func Detach(ctx context.Context) context.Context {
+	detached := context.WithoutCancel(ctx)
+	if e, ok := ctx.Value(eKey).(cloner); ok {
+		detached = context.WithValue(detached, eKey, e.clone())
+	}
+
+	return detached
+}
+
+// snapshot returns a deep copy of the data of the log entry in the context,
+// holding the log entry's lock so that concurrent updates to its counters are
+// not torn.
+func snapshot[T any](ctx context.Context) (*T, bool) {
+	entry := getEntry[T](ctx)
+	if entry == nil {
+		return nil, false
+	}
+
+	entry.metrics.Lock()
+	defer entry.metrics.Unlock()
+
+	return deepCopy(reflect.ValueOf(entry.data)).Interface().(*T), true
+}
+
+// cloner is implemented by log entries of every type.
+type cloner interface {
+	clone() any
+}
+
+// clone copies the log entry so that the copy can be changed and printed
+// independently, holding the log entry's lock so that concurrent updates to
+// its counters are not torn.
+func (e *entry[T]) clone() any {
+	e.metrics.Lock()
+	defer e.metrics.Unlock()
+
+	data := deepCopy(reflect.ValueOf(e.data)).Interface().(*T)
+
+	return &entry[T]{
+		level:      e.level,
+		leveled:    e.leveled,
+		hooks:      e.hooks,
+		timer:      e.timer,
+		start:      e.start,
+		msgKey:     e.msgKey,
+		collisions: e.collisions,
+		validators: e.validators,
+		emf:        append([]emfRecord(nil), e.emf...),
+		caller:     e.caller.clone(),
+		data:       data,
+	}
+}
+
+// deepCopy copies a value, along with the values that its maps, slices,
+// pointers, interfaces and exported struct fields refer to.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+
+	default:
+		return v
+	}
+}
@@ -0,0 +1,163 @@
+package logs
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DurationStyle determines how durations are written into printed log entries.
+type DurationStyle int
+
+const (
+	// DurationNanos writes durations as an integer number of nanoseconds,
+	// which is how time.Duration marshals to JSON. This is the default.
+	DurationNanos DurationStyle = iota
+	// DurationMillis writes durations as a number of milliseconds, such as
+	// 12.5.
+	DurationMillis
+	// DurationSeconds writes durations as a number of seconds, such as 0.0125.
+	DurationSeconds
+	// DurationString writes durations as strings, such as "12.5ms", using
+	// time.Duration's String method.
+	DurationString
+)
+
+// format writes a duration in the style.
+func (s DurationStyle) format(d time.Duration) any {
+	switch s {
+	case DurationMillis:
+		return float64(d) / float64(time.Millisecond)
+	case DurationSeconds:
+		return d.Seconds()
+	case DurationString:
+		return d.String()
+	default:
+		return int64(d)
+	}
+}
+
+// parse reads a duration that was written in the style and decoded from JSON
+// using json.Number. Durations written as strings are read in any style.
+func (s DurationStyle) parse(v any) (time.Duration, bool) {
+	switch v := v.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		return d, err == nil
+	case json.Number:
+		if s == DurationNanos {
+			n, err := v.Int64()
+			return time.Duration(n), err == nil
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+
+		switch s {
+		case DurationMillis:
+			return time.Duration(f * float64(time.Millisecond)), true
+		case DurationSeconds:
+			return time.Duration(f * float64(time.Second)), true
+		}
+	}
+
+	return 0, false
+}
+
+// WithDurationFormat sets how the time.Duration values of the log entry are
+// printed, such as the "duration" of the middleware's HTTP data, so that log
+// backends can read and graph them. Durations are found in the fields of
+// structs, including embedded structs, and in maps with string keys such as a
+// [FreeformEntry]. Durations within slices are not changed. The log entry
+// itself is not modified.
+//
+// This option uses reflection to inspect the log entry and requires decoding
+// and re-encoding the marshaled JSON, which adds a cost to every print.
+func WithDurationFormat(style DurationStyle) PrintOption {
+	return func(o *option) {
+		o.durations = style
+	}
+}
+
+// DurationFormat sets how the durations of log entries produced by the
+// middleware are printed, as described by [WithDurationFormat].
+func DurationFormat(style DurationStyle) MiddlewareOption {
+	return MiddlewareOption(WithDurationFormat(style))
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// durationPaths finds the dot-notation keys of the durations within a value.
+func durationPaths(v reflect.Value, prefix string, paths []string) []string {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return paths
+		}
+		return durationPaths(v.Elem(), prefix, paths)
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return paths
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			paths = durationPaths(iter.Value(), prefix+iter.Key().String()+".", paths)
+		}
+
+	case reflect.Struct:
+		rt := v.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+
+			name, _, _ := strings.Cut(tag, ",")
+			if field.Anonymous && name == "" {
+				paths = durationPaths(v.Field(i), prefix, paths)
+				continue
+			}
+
+			if !field.IsExported() {
+				continue
+			}
+
+			if name == "" {
+				name = field.Name
+			}
+
+			paths = durationPaths(v.Field(i), prefix+name+".", paths)
+		}
+
+	case reflect.Int64:
+		if v.Type() == durationType {
+			paths = append(paths, strings.TrimSuffix(prefix, "."))
+		}
+	}
+
+	return paths
+}
+
+// formatDurations rewrites the durations of the log entry within the map.
+func formatDurations(m map[string]any, v any, style DurationStyle) {
+	for _, key := range durationPaths(reflect.ValueOf(v), "", nil) {
+		value, ok := lookupPath(m, key)
+		if !ok {
+			continue
+		}
+
+		n, ok := value.(json.Number)
+		if !ok {
+			continue
+		}
+
+		if ns, err := n.Int64(); err == nil {
+			setPath(m, key, style.format(time.Duration(ns)))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package logs
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ECSVersion is the version of the Elastic Common Schema that [ECSEncoder]
+// writes into each log entry.
+const ECSVersion = "8.11.0"
+
+// ECSEncoder formats log entries as JSON objects using the field names of the
+// Elastic Common Schema, so that they can be ingested by Elasticsearch without
+// an ingest pipeline. The level is written as "log.level", the time as
+// "@timestamp", and the HTTP data that the middleware writes under the "@http"
+// key, the error data that [AddError] writes under the "@error" key, and the
+// trace fields added by the otellog package's WithTrace option are mapped to
+// their ECS equivalents. Any fields of the HTTP and error data that have no
+// equivalents remain under "@http" and "@error".
+type ECSEncoder struct{}
+
+// ECSFormat configures printing to format log entries using an [ECSEncoder].
+// To use this format with the middleware, provide the [Encoding] option
+// instead.
+func ECSFormat() PrintOption {
+	return func(o *option) {
+		o.encoder = ECSEncoder{}
+	}
+}
+
+// Encode formats the log entry as a JSON object using ECS field names.
+func (ECSEncoder) Encode(meta Metadata, entry any) ([]byte, error) {
+	data, err := marshalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := toMap(data)
+	if !ok {
+		m = map[string]any{"message": json.RawMessage(data)}
+	}
+
+	for _, f := range meta.Fields {
+		switch f.Key {
+		case "trace_id":
+			setPath(m, "trace.id", f.Value)
+		case "span_id":
+			setPath(m, "span.id", f.Value)
+		case "trace_flags":
+		default:
+			m[f.Key] = f.Value
+		}
+	}
+
+	if http, ok := m["@http"].(map[string]any); ok {
+		ecsHttp(m, http, meta.DurationFormat)
+		if len(http) == 0 {
+			delete(m, "@http")
+		}
+	}
+
+	if e, ok := m["@error"].(map[string]any); ok {
+		moveFields(m, e, map[string]string{
+			"message": "error.message",
+			"type":    "error.type",
+			"stack":   "error.stack_trace",
+		})
+		if len(e) == 0 {
+			delete(m, "@error")
+		}
+	}
+
+	meta.TimeFormat = time.RFC3339Nano
+	m["@timestamp"] = meta.FormatTime()
+	setPath(m, "log.level", strings.ToLower(meta.Level.String()))
+	setPath(m, "ecs.version", ECSVersion)
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+// ecsHttp moves the fields of the middleware's HTTP data that have ECS
+// equivalents into the log entry. The duration is read in the style that it
+// was written, and moved as a number of nanoseconds.
+func ecsHttp(m, http map[string]any, durations DurationStyle) {
+	moveFields(m, http, map[string]string{
+		"method":         "http.request.method",
+		"path":           "url.path",
+		"request_id":     "http.request.id",
+		"remote_addr":    "source.address",
+		"client_ip":      "client.ip",
+		"user_agent":     "user_agent.original",
+		"referer":        "http.request.referrer",
+		"status":         "http.response.status_code",
+		"response_bytes": "http.response.body.bytes",
+	})
+
+	if d, ok := durations.parse(http["duration"]); ok {
+		setPath(m, "event.duration", int64(d))
+		delete(http, "duration")
+	}
+
+	if headers, ok := http["headers"].(map[string]any); ok {
+		if ua, ok := headers["User-Agent"]; ok {
+			setPath(m, "user_agent.original", ua)
+		}
+	}
+}
+
+// moveFields moves fields from a nested map into the log entry, using the
+// names given, which may use dot notation.
+func moveFields(m, from map[string]any, names map[string]string) {
+	for k, name := range names {
+		if v, ok := from[k]; ok {
+			setPath(m, name, v)
+			delete(from, k)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package logs
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// emf is configuration for wrapping log entries in CloudWatch Embedded Metric
+// Format metadata.
+type emf struct {
+	namespace string
+	metrics   []emfMetric
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// WithEMF configures printing to include the "_aws" metadata required by the
+// CloudWatch Embedded Metric Format, so that the named fields of each log entry
+// are extracted as metrics in the namespace. A metric's unit may be specified
+// by appending it to the field name after a colon, such as
+// "latency:Milliseconds". Otherwise its unit is "None".
+//
+// Metrics must be top-level fields of the log entry, and metrics that are
+// missing from a log entry are not included in its metadata. All other fields
+// remain in the log entry as properties. No dimensions are declared.
+func WithEMF(namespace string, metrics ...string) PrintOption {
+	e := &emf{namespace: namespace}
+	for _, m := range metrics {
+		name, unit, ok := strings.Cut(m, ":")
+		if !ok {
+			unit = "None"
+		}
+		e.metrics = append(e.metrics, emfMetric{Name: name, Unit: unit})
+	}
+
+	return func(o *option) {
+		o.emf = e
+	}
+}
+
+// apply adds the "_aws" metadata to a log entry.
+func (e *emf) apply(m map[string]any, o option) {
+	metrics := []emfMetric{}
+	for _, metric := range e.metrics {
+		if _, ok := m[metric.Name]; ok {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	addEMFDirective(m, o, map[string]any{
+		"Namespace":  e.namespace,
+		"Dimensions": [][]string{{}},
+		"Metrics":    metrics,
+	})
+}
+
+// emfRecord is a metric recorded in a log entry using [EMF].
+type emfRecord struct {
+	namespace  string
+	metric     emfMetric
+	dimensions []string
+}
+
+// recordEMF records a metric in the freeform log entry in the context. The
+// metric's value and dimensions are written into the log entry right away, and
+// its declaration is kept with the log entry until it is printed.
+func recordEMF(ctx context.Context, namespace, name string, value float64, unit string, dimensions ...string) bool {
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return false
+	}
+
+	if unit == "" {
+		unit = "None"
+	}
+
+	entry.metrics.Lock()
+	defer entry.metrics.Unlock()
+
+	e := *entry.data
+	record := emfRecord{namespace: namespace, metric: emfMetric{Name: name, Unit: unit}}
+
+	repeated := slices.ContainsFunc(entry.emf, func(r emfRecord) bool {
+		return r.metric.Name == name
+	})
+
+	if values, ok := e[name].([]float64); ok && repeated {
+		e[name] = append(values, value)
+	} else if current, ok := e[name].(float64); ok && repeated {
+		e[name] = []float64{current, value}
+	} else {
+		e[name] = value
+	}
+
+	for i := 0; i+1 < len(dimensions); i += 2 {
+		e[dimensions[i]] = dimensions[i+1]
+		record.dimensions = append(record.dimensions, dimensions[i])
+	}
+
+	entry.emf = append(entry.emf, record)
+	return true
+}
+
+// emfRecords copies the metrics recorded in the log entry using [EMF].
+func (e *entry[T]) emfRecords() []emfRecord {
+	e.metrics.Lock()
+	defer e.metrics.Unlock()
+
+	return append([]emfRecord(nil), e.emf...)
+}
+
+// applyRecords adds the "_aws" metadata for metrics recorded using [EMF] to a
+// log entry. Metrics that share a namespace and set of dimensions are declared
+// together.
+func applyRecords(m map[string]any, o option) {
+	type group struct {
+		namespace  string
+		dimensions []string
+		metrics    []emfMetric
+	}
+
+	var groups []*group
+	for _, r := range o.emfRecords {
+		var g *group
+		for _, candidate := range groups {
+			if candidate.namespace == r.namespace && slices.Equal(candidate.dimensions, r.dimensions) {
+				g = candidate
+				break
+			}
+		}
+
+		if g == nil {
+			g = &group{namespace: r.namespace, dimensions: r.dimensions}
+			groups = append(groups, g)
+		}
+
+		if !slices.ContainsFunc(g.metrics, func(metric emfMetric) bool { return metric.Name == r.metric.Name }) {
+			g.metrics = append(g.metrics, r.metric)
+		}
+	}
+
+	for _, g := range groups {
+		dimensions := g.dimensions
+		if dimensions == nil {
+			dimensions = []string{}
+		}
+
+		addEMFDirective(m, o, map[string]any{
+			"Namespace":  g.namespace,
+			"Dimensions": [][]string{dimensions},
+			"Metrics":    g.metrics,
+		})
+	}
+}
+
+// addEMFDirective adds a metric directive to the "_aws" metadata of a log
+// entry, creating the metadata if necessary.
+func addEMFDirective(m map[string]any, o option, directive map[string]any) {
+	aws, ok := m["_aws"].(map[string]any)
+	if !ok {
+		aws = map[string]any{"Timestamp": o.timer.Now().UnixMilli()}
+		m["_aws"] = aws
+	}
+
+	directives, _ := aws["CloudWatchMetrics"].([]map[string]any)
+	aws["CloudWatchMetrics"] = append(directives, directive)
+}
@@ -0,0 +1,226 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata describes a log entry that is being printed.
+type Metadata struct {
+	Level  Level
+	Time   time.Time
+	Fields []MetaField
+
+	// LevelKey and TimeKey are the keys set using [WithMetadataKeys]. If they
+	// are empty, the encoder chooses its own keys.
+	LevelKey string
+	TimeKey  string
+
+	// TimeFormat is the layout set using [WithTimeFormat]. Use
+	// [Metadata.FormatTime] to format the time accordingly.
+	TimeFormat string
+
+	// DurationFormat is the style set using [WithDurationFormat], in which the
+	// durations of the log entry are written.
+	DurationFormat DurationStyle
+}
+
+const (
+	// TimeUnix is a time format that prints times as the number of seconds
+	// since the Unix epoch.
+	TimeUnix = "unix"
+
+	// TimeUnixMilli is a time format that prints times as the number of
+	// milliseconds since the Unix epoch.
+	TimeUnixMilli = "unixmilli"
+)
+
+// FormatTime formats the time using the layout in TimeFormat. Times are
+// formatted as strings using time.RFC3339 by default, or as numbers if the
+// layout is [TimeUnix] or [TimeUnixMilli].
+func (m Metadata) FormatTime() any {
+	switch m.TimeFormat {
+	case "":
+		return m.Time.Format(time.RFC3339)
+	case TimeUnix:
+		return m.Time.Unix()
+	case TimeUnixMilli:
+		return m.Time.UnixMilli()
+	default:
+		return m.Time.Format(m.TimeFormat)
+	}
+}
+
+// keys provides the level and time keys, using the defaults if they are not
+// set.
+func (m Metadata) keys(level, time string) (string, string) {
+	if m.LevelKey != "" {
+		level = m.LevelKey
+	}
+	if m.TimeKey != "" {
+		time = m.TimeKey
+	}
+
+	return level, time
+}
+
+// WithMetadataKeys renames the level and time fields of printed log entries,
+// which are "@level" and "@time" by default, to match the schema expected by a
+// downstream system. An empty key leaves that field's name unchanged.
+func WithMetadataKeys(level, time string) PrintOption {
+	return func(o *option) {
+		o.levelKey = level
+		o.timeKey = time
+	}
+}
+
+// WithTimeFormat sets the layout used to format the time field of printed log
+// entries, such as time.RFC3339Nano. Use [TimeUnix] or [TimeUnixMilli] to print
+// the time as a number. The default is time.RFC3339.
+func WithTimeFormat(layout string) PrintOption {
+	return func(o *option) {
+		o.timeFormat = layout
+	}
+}
+
+// MetadataKeys renames the level and time fields of log entries printed by the
+// middleware, as described by [WithMetadataKeys].
+func MetadataKeys(level, time string) MiddlewareOption {
+	return MiddlewareOption(WithMetadataKeys(level, time))
+}
+
+// TimeFormat sets the layout used to format the time field of log entries
+// printed by the middleware, as described by [WithTimeFormat].
+func TimeFormat(layout string) MiddlewareOption {
+	return MiddlewareOption(WithTimeFormat(layout))
+}
+
+// Encoder formats log entries as they are printed. The entry is provided as its
+// JSON encoding, in a json.RawMessage, after any print-time options such as
+// [WithRedaction] have been applied. Encode must return a single line,
+// including its trailing newline.
+type Encoder interface {
+	Encode(meta Metadata, entry any) ([]byte, error)
+}
+
+// WithEncoder sets the encoder used to format the log entry. The default is a
+// [JSONEncoder].
+func WithEncoder(enc Encoder) PrintOption {
+	return func(o *option) {
+		o.encoder = enc
+	}
+}
+
+// Encoding sets the encoder used to format log entries printed by the
+// middleware. The default is a [JSONEncoder].
+func Encoding(enc Encoder) MiddlewareOption {
+	return MiddlewareOption(WithEncoder(enc))
+}
+
+// JSONEncoder formats log entries as JSON objects, with the "@level" and
+// "@time" fields, and any additional meta fields, ahead of the log entry's own
+// fields. The order of the log entry's fields is preserved.
+type JSONEncoder struct{}
+
+// Encode formats the log entry as a JSON object.
+func (JSONEncoder) Encode(meta Metadata, entry any) ([]byte, error) {
+	data, err := marshalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Index(data, []byte("{")) == 0 {
+		levelKey, timeKey := meta.keys("@level", "@time")
+		line := []byte(fmt.Sprintf(`{%s:%s,%s:%s`,
+			jsonValue(levelKey), jsonValue(meta.Level.String()),
+			jsonValue(timeKey), jsonValue(meta.FormatTime()),
+		))
+		line = appendMeta(line, meta.Fields)
+		if bytes.Index(data, []byte("}")) != 1 {
+			line = append(line, ',')
+		}
+		data = append(line, data[1:]...)
+		data = append(data, '\n')
+	}
+
+	return data, nil
+}
+
+// LogfmtEncoder formats log entries as logfmt lines of key=value pairs,
+// starting with the "level" and "time" keys, and any additional meta fields.
+// The log entry's fields follow in sorted order, with nested fields flattened
+// using dot notation.
+type LogfmtEncoder struct{}
+
+// Encode formats the log entry as a logfmt line.
+func (LogfmtEncoder) Encode(meta Metadata, entry any) ([]byte, error) {
+	data, err := marshalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	levelKey, timeKey := meta.keys("level", "time")
+	writePair(&buf, levelKey, meta.Level.String())
+	writePair(&buf, timeKey, meta.FormatTime())
+
+	for _, f := range meta.Fields {
+		writePair(&buf, f.Key, f.Value)
+	}
+
+	if m, ok := toMap(data); ok {
+		fields := make(map[string]any)
+		flatten("", m, fields)
+		for _, k := range sortedKeys(fields) {
+			writePair(&buf, k, fields[k])
+		}
+	} else {
+		writePair(&buf, "msg", string(bytes.TrimSpace(data)))
+	}
+
+	buf.WriteByte('\n')
+	return bytes.TrimPrefix(buf.Bytes(), []byte(" ")), nil
+}
+
+// marshalEntry provides the JSON encoding of a log entry.
+func marshalEntry(entry any) ([]byte, error) {
+	if data, ok := entry.(json.RawMessage); ok {
+		return data, nil
+	}
+
+	return json.Marshal(entry)
+}
+
+// jsonValue encodes a metadata value as JSON.
+func jsonValue(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// writePair writes a space-separated key=value pair.
+func writePair(buf *bytes.Buffer, key string, value any) {
+	buf.WriteByte(' ')
+	if strings.ContainsAny(key, " =\"\t\n") {
+		buf.WriteString(strconv.Quote(key))
+	} else {
+		buf.WriteString(key)
+	}
+	buf.WriteByte('=')
+	buf.WriteString(consoleValue(value))
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
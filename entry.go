@@ -0,0 +1,165 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FreeformEntry is a freeform log entry.
+type FreeformEntry map[string]any
+
+func newFreeformEntry() *FreeformEntry { return &FreeformEntry{} }
+
+type keyValue struct {
+	Key   string
+	Value any
+}
+
+func (kv keyValue) adjust(e FreeformEntry, adj func(map[string]any, string)) {
+	kv.adjustWith(e, CollisionOverwrite, adj)
+}
+
+// adjustWith calls adj with the map that holds the last part of the key,
+// creating the maps along the way. A value along the way that is not a map is
+// handled according to the policy.
+func (kv keyValue) adjustWith(e FreeformEntry, policy CollisionPolicy, adj func(map[string]any, string)) error {
+	split := strings.Split(kv.Key, ".")
+
+	current := map[string]any(e)
+	for _, sub := range split[:len(split)-1] {
+		existing, exists := current[sub]
+		if nested, ok := asMap(existing); ok {
+			current = nested
+			continue
+		}
+
+		if exists && existing != nil {
+			switch policy {
+			case CollisionError:
+				return fmt.Errorf("%w: %q is not a map", ErrKeyCollision, sub)
+			case CollisionRename:
+				current[renamedKey(current, sub)] = existing
+			}
+		}
+
+		m := make(map[string]any)
+		current[sub] = m
+		current = m
+	}
+
+	adj(current, split[len(split)-1])
+	return nil
+}
+
+type keyValues []keyValue
+
+func (k keyValues) adjust(e FreeformEntry) {
+	k.adjustWith(e, CollisionOverwrite)
+}
+
+// adjustWith sets each key-value pair, handling collisions according to the
+// policy. The function returns the first collision error, after setting the
+// pairs that did not collide.
+func (k keyValues) adjustWith(e FreeformEntry, policy CollisionPolicy) error {
+	var first error
+	for _, kv := range k {
+		err := kv.adjustWith(e, policy, func(m map[string]any, k string) {
+			m[k] = kv.Value
+		})
+		if first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+func toKeyValues(args ...any) keyValues {
+	kvs := make([]keyValue, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		if key, ok := args[i].(string); ok {
+			kvs[i/2] = keyValue{Key: key, Value: args[i+1]}
+		}
+	}
+	return kvs
+}
+
+// scopedValue records the state of a key in a freeform log entry before it was
+// added by [With], so that the state can be restored.
+type scopedValue struct {
+	path     []string
+	depth    int
+	prev     any
+	existed  bool
+	shadowed any
+	replaced bool
+}
+
+func newScopedValue(e FreeformEntry, key string) scopedValue {
+	s := scopedValue{path: strings.Split(key, ".")}
+	last := len(s.path) - 1
+
+	for s.depth < last {
+		if _, ok := nestedMap(e, s.path[:s.depth+1]); !ok {
+			break
+		}
+		s.depth++
+	}
+
+	parent, _ := nestedMap(e, s.path[:s.depth])
+	if s.depth == last {
+		s.prev, s.existed = parent[s.path[last]]
+	} else {
+		s.shadowed, s.replaced = parent[s.path[s.depth]]
+	}
+
+	return s
+}
+
+func (s scopedValue) restore(e FreeformEntry) {
+	last := len(s.path) - 1
+
+	parent, ok := nestedMap(e, s.path[:last])
+	if !ok {
+		return
+	}
+
+	if s.existed {
+		parent[s.path[last]] = s.prev
+		return
+	}
+
+	delete(parent, s.path[last])
+
+	// Remove any maps that were created to hold the key.
+	for i := last; i > s.depth; i-- {
+		m, ok := nestedMap(e, s.path[:i])
+		if !ok || len(m) > 0 {
+			return
+		}
+
+		parent, _ := nestedMap(e, s.path[:i-1])
+		if i == s.depth+1 && s.replaced {
+			parent[s.path[i-1]] = s.shadowed
+		} else {
+			delete(parent, s.path[i-1])
+		}
+	}
+}
+
+// nestedMap finds the map at the given path within a freeform log entry.
+func nestedMap(e FreeformEntry, path []string) (map[string]any, bool) {
+	current := map[string]any(e)
+	for _, sub := range path {
+		switch next := current[sub].(type) {
+		case FreeformEntry:
+			current = next
+		case map[string]any:
+			current = next
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
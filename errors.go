@@ -0,0 +1,76 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ErrorData is the data structure that [AddError] writes into a log entry
+// under the "@error" key. It may also be used as a field of a custom log entry
+// type and filled using [RecordError].
+type ErrorData struct {
+	Message string       `json:"message"`
+	Type    string       `json:"type"`
+	Chain   []ErrorCause `json:"chain,omitempty"`
+	Stack   string       `json:"stack,omitempty"`
+}
+
+// ErrorCause describes one of the errors wrapped by a recorded error.
+type ErrorCause struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// ErrorOption is a configuration option for recording errors.
+type ErrorOption func(*option)
+
+// WithStack configures error recording to include a stack trace of the
+// goroutine that recorded the error.
+func WithStack() ErrorOption {
+	return func(o *option) {
+		o.stack = true
+	}
+}
+
+// NewErrorData describes an error, including the chain of errors that it wraps
+// as found by errors.Unwrap.
+func NewErrorData(err error, opts ...ErrorOption) ErrorData {
+	opt := applyOptions(opts...)
+
+	data := ErrorData{Message: err.Error(), Type: fmt.Sprintf("%T", err)}
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		data.Chain = append(data.Chain, ErrorCause{
+			Message: cause.Error(),
+			Type:    fmt.Sprintf("%T", cause),
+		})
+	}
+
+	if opt.stack {
+		stack := make([]byte, 64<<10)
+		data.Stack = string(stack[:runtime.Stack(stack, false)])
+	}
+
+	return data
+}
+
+// RecordError creates an adjustment for a custom log entry type that writes a
+// description of the error into the [ErrorData] field chosen by the selector.
+// Use it with [Logger.Adjust], and use [Logger.Error] to set the log entry's
+// level. A nil error leaves the log entry unchanged.
+func RecordError[T any](selector func(*T) *ErrorData, err error, opts ...ErrorOption) func(*T) {
+	var data ErrorData
+	if err != nil {
+		data = NewErrorData(err, opts...)
+	}
+
+	return func(e *T) {
+		if err == nil {
+			return
+		}
+
+		if field := selector(e); field != nil {
+			*field = data
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package logs
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxPooledBuffer is the capacity above which a line buffer is not returned to
+// the pool, so that one very large log entry does not pin its memory.
+const maxPooledBuffer = 64 << 10
+
+// bufferPool holds the buffers that freeform log entries are encoded into.
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 1024)
+		return &buf
+	},
+}
+
+// writeFast encodes a freeform log entry directly into a pooled buffer and
+// writes it, skipping the separate marshal and re-encode steps of the
+// [JSONEncoder]. The function will return false if the log entry cannot take
+// this path, in which case nothing has been written. The output is identical
+// to that of the slower path.
+func writeFast(v any, meta Metadata, o option) (bool, error) {
+	e, ok := v.(*FreeformEntry)
+	if !ok || !o.streams(v) {
+		return false, nil
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer func() {
+		if cap(*buf) <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	line, ok := appendLine((*buf)[:0], meta, *e)
+	*buf = line
+	if !ok {
+		return false, nil
+	}
+
+	_, err := writeMeta(o.out, meta, line)
+	return true, err
+}
+
+// streams reports whether a log entry can be written by [writeFast], which
+// is only the case when it is printed as-is, as a single JSON line.
+func (o option) streams(v any) bool {
+	if _, ok := o.encoder.(JSONEncoder); !ok {
+		return false
+	}
+
+	if _, ok := o.out.(*Router); ok {
+		return false
+	}
+
+	return o.human == nil && o.maxLine <= 0 && !o.reshapes(v)
+}
+
+// appendLine appends a freeform log entry to the buffer in the form produced
+// by the [JSONEncoder].
+func appendLine(buf []byte, meta Metadata, e FreeformEntry) ([]byte, bool) {
+	levelKey, timeKey := meta.keys("@level", "@time")
+
+	buf = append(buf, '{')
+	buf = appendString(buf, levelKey)
+	buf = append(buf, ':')
+	buf = appendString(buf, meta.Level.String())
+	buf = append(buf, ',')
+	buf = appendString(buf, timeKey)
+	buf = append(buf, ':')
+	buf = appendTime(buf, meta)
+	buf = appendMeta(buf, meta.Fields)
+
+	if len(e) > 0 {
+		buf = append(buf, ',')
+	}
+
+	buf, ok := appendFields(buf, e)
+	if !ok {
+		return buf, false
+	}
+
+	return append(buf, '}', '\n'), true
+}
+
+// appendTime appends the metadata's time, formatted as [Metadata.FormatTime]
+// would format it.
+func appendTime(buf []byte, meta Metadata) []byte {
+	switch meta.TimeFormat {
+	case "":
+		buf = append(buf, '"')
+		buf = meta.Time.AppendFormat(buf, time.RFC3339)
+		return append(buf, '"')
+	case TimeUnix:
+		return strconv.AppendInt(buf, meta.Time.Unix(), 10)
+	case TimeUnixMilli:
+		return strconv.AppendInt(buf, meta.Time.UnixMilli(), 10)
+	default:
+		return appendString(buf, meta.Time.Format(meta.TimeFormat))
+	}
+}
+
+// appendFields appends the fields of a map to the buffer in sorted key order,
+// without the enclosing braces, as json.Marshal would.
+func appendFields(buf []byte, m map[string]any) ([]byte, bool) {
+	var stack [32]string
+	keys := stack[:0]
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var ok bool
+	for i, k := range keys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		buf = appendString(buf, k)
+		buf = append(buf, ':')
+		if buf, ok = appendValue(buf, m[k]); !ok {
+			return buf, false
+		}
+	}
+
+	return buf, true
+}
+
+// appendValue appends a value to the buffer as JSON. Common types are written
+// directly; anything else is marshaled using json.Marshal. The function will
+// return false if the value cannot be marshaled.
+func appendValue(buf []byte, v any) ([]byte, bool) {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "null"...), true
+	case string:
+		return appendString(buf, v), true
+	case bool:
+		return strconv.AppendBool(buf, v), true
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10), true
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10), true
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10), true
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10), true
+	case int64:
+		return strconv.AppendInt(buf, v, 10), true
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10), true
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10), true
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10), true
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10), true
+	case uint64:
+		return strconv.AppendUint(buf, v, 10), true
+	case float64:
+		return appendFloat(buf, v, 64)
+	case float32:
+		return appendFloat(buf, float64(v), 32)
+	case FreeformEntry:
+		return appendObject(buf, v)
+	case map[string]any:
+		return appendObject(buf, v)
+	case []string:
+		if v == nil {
+			return append(buf, "null"...), true
+		}
+
+		buf = append(buf, '[')
+		for i, s := range v {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendString(buf, s)
+		}
+		return append(buf, ']'), true
+	case []any:
+		if v == nil {
+			return append(buf, "null"...), true
+		}
+
+		var ok bool
+		buf = append(buf, '[')
+		for i, item := range v {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			if buf, ok = appendValue(buf, item); !ok {
+				return buf, false
+			}
+		}
+		return append(buf, ']'), true
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return buf, false
+		}
+		return append(buf, data...), true
+	}
+}
+
+// appendObject appends a map to the buffer as a JSON object.
+func appendObject(buf []byte, m map[string]any) ([]byte, bool) {
+	if m == nil {
+		return append(buf, "null"...), true
+	}
+
+	buf, ok := appendFields(append(buf, '{'), m)
+	return append(buf, '}'), ok
+}
+
+// appendFloat appends a float as json.Marshal would, using exponent notation
+// only for very small or very large values. NaN and infinite values cannot be
+// represented in JSON.
+func appendFloat(buf []byte, f float64, bits int) ([]byte, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return buf, false
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	buf = strconv.AppendFloat(buf, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9, as json.Marshal does.
+		if n := len(buf); n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+
+	return buf, true
+}
+
+// appendString appends a string to the buffer as JSON. Strings of printable
+// ASCII that need no escaping are written directly; any others are escaped by
+// json.Marshal, so that the output matches it exactly.
+func appendString(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7e || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			data, _ := json.Marshal(s)
+			return append(buf, data...)
+		}
+	}
+
+	buf = append(buf, '"')
+	buf = append(buf, s...)
+	return append(buf, '"')
+}
@@ -0,0 +1,56 @@
+package logs
+
+import (
+	"errors"
+	"os"
+)
+
+// FatalBehavior determines what happens after a log entry at FATAL level or
+// above is printed.
+type FatalBehavior int
+
+const (
+	// FatalContinue does nothing after a FATAL log entry is printed. This is
+	// the default.
+	FatalContinue FatalBehavior = iota
+	// ExitOnFatal exits the process with status 1 after a FATAL log entry is
+	// printed. Deferred functions are not run, so an [AsyncWriter] should be
+	// flushed before the log entry is printed.
+	ExitOnFatal
+	// PanicOnFatal panics with [ErrFatal] after a FATAL log entry is printed.
+	PanicOnFatal
+)
+
+// ErrFatal is the value that a goroutine panics with when a FATAL log entry is
+// printed using the [PanicOnFatal] behavior.
+var ErrFatal = errors.New("logs: fatal log entry printed")
+
+// WithFatalBehavior sets what happens after a log entry at FATAL level or above
+// is printed, so that printing a FATAL log entry can terminate the process like
+// it does in other logging libraries. Use it when printing a log entry right
+// after calling a function like [Fatal].
+func WithFatalBehavior(behavior FatalBehavior) PrintOption {
+	return func(o *option) {
+		o.fatal = behavior
+	}
+}
+
+// OnFatal sets what happens after the middleware prints a log entry at FATAL
+// level or above, as described by [WithFatalBehavior].
+func OnFatal(behavior FatalBehavior) MiddlewareOption {
+	return MiddlewareOption(WithFatalBehavior(behavior))
+}
+
+// terminate runs the configured behavior after a log entry has been printed.
+func (o option) terminate(level Level) {
+	if level < FATAL {
+		return
+	}
+
+	switch o.fatal {
+	case ExitOnFatal:
+		os.Exit(1)
+	case PanicOnFatal:
+		panic(ErrFatal)
+	}
+}
@@ -0,0 +1,568 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// toMap decodes a JSON object into a map. Numbers are preserved exactly as
+// they were encoded. The function will return false if the data is not a JSON
+// object.
+func toMap(data []byte) (map[string]any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil || m == nil {
+		return nil, false
+	}
+
+	return m, true
+}
+
+// lookupPath finds the value at a dot-notation key within nested maps.
+func lookupPath(m map[string]any, key string) (any, bool) {
+	split := strings.Split(key, ".")
+
+	current := m
+	for i, sub := range split {
+		v, ok := current[sub]
+		if !ok {
+			return nil, false
+		}
+
+		if i == len(split)-1 {
+			return v, true
+		}
+
+		if current, ok = v.(map[string]any); !ok {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+// setPath sets the value at a dot-notation key, creating nested maps as
+// necessary.
+func setPath(m map[string]any, key string, value any) {
+	split := strings.Split(key, ".")
+
+	current := m
+	for i, sub := range split {
+		if i == len(split)-1 {
+			current[sub] = value
+			return
+		}
+
+		nested, ok := current[sub].(map[string]any)
+		if !ok {
+			nested = make(map[string]any)
+			current[sub] = nested
+		}
+		current = nested
+	}
+}
+
+// deletePath removes the value at a dot-notation key within nested maps.
+func deletePath(m map[string]any, key string) {
+	parent, last := m, key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		v, ok := lookupPath(m, key[:i])
+		if !ok {
+			return
+		}
+		if parent, ok = v.(map[string]any); !ok {
+			return
+		}
+		last = key[i+1:]
+	}
+
+	delete(parent, last)
+}
+
+// expandPath finds the dot-notation keys within nested maps that match a key,
+// in which a "*" part matches any key at that level.
+func expandPath(m map[string]any, key string) []string {
+	if !strings.Contains(key, "*") {
+		return []string{key}
+	}
+
+	sub, rest, nested := strings.Cut(key, ".")
+
+	var names []string
+	if sub == "*" {
+		names = sortedKeys(m)
+	} else if _, ok := m[sub]; ok {
+		names = []string{sub}
+	}
+
+	var keys []string
+	for _, name := range names {
+		if !nested {
+			keys = append(keys, name)
+			continue
+		}
+
+		if child, ok := m[name].(map[string]any); ok {
+			for _, k := range expandPath(child, rest) {
+				keys = append(keys, name+"."+k)
+			}
+		}
+	}
+
+	return keys
+}
+
+// allowFields returns a copy of the map that contains only the specified
+// dot-notation keys.
+func allowFields(m map[string]any, keys []string) map[string]any {
+	allowed := make(map[string]any)
+	for _, pattern := range keys {
+		for _, key := range expandPath(m, pattern) {
+			if v, ok := lookupPath(m, key); ok {
+				setPath(allowed, key, v)
+			}
+		}
+	}
+
+	return allowed
+}
+
+// denyFields removes the specified dot-notation keys from the map.
+func denyFields(m map[string]any, keys []string) {
+	for _, pattern := range keys {
+		for _, key := range expandPath(m, pattern) {
+			deletePath(m, key)
+		}
+	}
+}
+
+// reshapes reports whether printing will adjust the fields of the log entry.
+func (o option) reshapes(v any) bool {
+	return o.allowlist != nil || o.denylist != nil || o.omitZero || o.fieldCount || o.emf != nil || len(o.emfRecords) > 0 || o.durations != DurationNanos || o.keyNormalizer != nil || o.redacts(v)
+}
+
+// reshape applies print-time field adjustments to a marshaled log entry
+// without mutating the log entry itself.
+func reshape(data []byte, v any, o option) ([]byte, error) {
+	if !o.reshapes(v) {
+		return data, nil
+	}
+
+	m, ok := toMap(data)
+	if !ok {
+		return data, nil
+	}
+
+	if o.redacts(v) {
+		redact(m, "", taggedFields(v), o)
+	}
+
+	if o.durations != DurationNanos {
+		formatDurations(m, v, o.durations)
+	}
+
+	if o.omitZero {
+		for _, key := range zeroFields(reflect.ValueOf(v), nil) {
+			delete(m, key)
+		}
+	}
+
+	if o.keyNormalizer != nil {
+		m = normalizeKeys(m, o.keyNormalizer)
+	}
+
+	if o.allowlist != nil {
+		m = allowFields(m, o.allowlist)
+	}
+
+	if o.denylist != nil {
+		denyFields(m, o.denylist)
+	}
+
+	if o.fieldCount {
+		m["@field_count"] = len(m)
+	}
+
+	if o.emf != nil {
+		o.emf.apply(m, o)
+	}
+
+	if len(o.emfRecords) > 0 {
+		applyRecords(m, o)
+	}
+
+	return json.Marshal(m)
+}
+
+// setStructPath sets the value at a dot-notation key within a struct, following
+// nested structs, pointers to structs, and maps with string keys. Each part of
+// the key may be either a field's name or the name in its json struct tag. Nil
+// pointers and maps along the way are allocated. A nil value sets the field to
+// its zero value. The function will return false if the key cannot be found or
+// the value cannot be assigned to the field.
+func setStructPath(rv reflect.Value, key string, value any) bool {
+	sub, rest, nested := strings.Cut(key, ".")
+
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			if !rv.CanSet() {
+				return false
+			}
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		field, ok := structField(rv, sub)
+		if !ok {
+			return false
+		}
+		if nested {
+			return setStructPath(field, rest, value)
+		}
+		return assign(field, value)
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		if rv.IsNil() {
+			if !rv.CanSet() {
+				return false
+			}
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+
+		mapKey := reflect.ValueOf(sub).Convert(rv.Type().Key())
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		if current := rv.MapIndex(mapKey); current.IsValid() {
+			elem.Set(current)
+		}
+
+		if nested && !setStructPath(elem, rest, value) {
+			return false
+		}
+		if !nested && !assign(elem, value) {
+			return false
+		}
+
+		rv.SetMapIndex(mapKey, elem)
+		return true
+	}
+
+	return false
+}
+
+// structField finds the settable field of a struct by its name, or by the name
+// in its json struct tag. Fields of embedded structs are included.
+func structField(rv reflect.Value, name string) (reflect.Value, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Name == name || (tag == name && tag != "-") {
+			return rv.Field(i), true
+		}
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+
+		embedded := rv.Field(i)
+		if embedded.Kind() == reflect.Pointer {
+			if embedded.IsNil() {
+				continue
+			}
+			embedded = embedded.Elem()
+		}
+
+		if embedded.Kind() == reflect.Struct {
+			if v, ok := structField(embedded, name); ok {
+				return v, true
+			}
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// assign sets the field to the value, converting between compatible types such
+// as numeric kinds.
+func assign(field reflect.Value, value any) bool {
+	if !field.CanSet() {
+		return false
+	}
+
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(field.Type()):
+		field.Set(v)
+	case v.Type().ConvertibleTo(field.Type()) && (v.Kind() == reflect.String) == (field.Kind() == reflect.String):
+		field.Set(v.Convert(field.Type()))
+	default:
+		return false
+	}
+
+	return true
+}
+
+// zeroFields uses reflection to find the JSON keys of a struct's fields that
+// hold zero values. Fields of embedded structs are included, even if the
+// embedded struct's type is unexported, but nested structs are not inspected.
+func zeroFields(rv reflect.Value, keys []string) []string {
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return keys
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return keys
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			keys = zeroFields(rv.Field(i), keys)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if rv.Field(i).IsZero() {
+			keys = append(keys, name)
+		}
+	}
+
+	return keys
+}
+
+// fitLine encodes a marshaled log entry with its metadata. If the line would
+// exceed the configured maximum size, the entry is reduced or split according
+// to the configured [Overflow].
+func fitLine(data []byte, meta Metadata, o option) ([]byte, error) {
+	line, err := o.encoder.Encode(meta, json.RawMessage(data))
+	if err != nil || o.maxLine <= 0 || len(line) <= o.maxLine {
+		return line, err
+	}
+
+	m, ok := toMap(data)
+	if !ok {
+		return line, nil
+	}
+
+	switch o.overflow {
+	case OverflowSplit:
+		return splitLine(line, m, meta, o)
+	case OverflowTruncate:
+		return truncateLine(line, m, meta, o)
+	default:
+		return dropFields(line, m, meta, o, nil)
+	}
+}
+
+// encodeMap encodes a log entry that has been decoded into a map.
+func encodeMap(m map[string]any, meta Metadata, o option) ([]byte, bool, error) {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	line, err := o.encoder.Encode(meta, json.RawMessage(body))
+	return line, true, err
+}
+
+// dropFields removes the entry's largest fields until the line fits within the
+// configured maximum size. The removed keys are added to those already
+// removed and recorded under "@truncated", or it is set to true if fields were
+// dropped using [WithMaxLineBytes].
+func dropFields(line []byte, m map[string]any, meta Metadata, o option, removed []string) ([]byte, error) {
+	for {
+		if o.overflow == 0 {
+			m["@truncated"] = true
+		} else {
+			m["@truncated"] = removed
+		}
+
+		next, ok, err := encodeMap(m, meta, o)
+		if !ok {
+			return line, nil
+		}
+		if line = next; err != nil {
+			return nil, err
+		}
+		if len(line) <= o.maxLine || len(m) == 1 {
+			return line, nil
+		}
+
+		key := largestField(m)
+		delete(m, key)
+		removed = append(removed, key)
+	}
+}
+
+// truncationSuffix marks the end of a string field that has been shortened.
+const truncationSuffix = "..."
+
+// truncateLine shortens the entry's longest string fields until the line fits
+// within the configured maximum size, recording their keys under "@truncated".
+// If the line still does not fit once every string field has been shortened,
+// the largest fields are removed.
+func truncateLine(line []byte, m map[string]any, meta Metadata, o option) ([]byte, error) {
+	var removed []string
+	for {
+		parent, key, path := longestString(m, "")
+		if parent == nil {
+			return dropFields(line, m, meta, o, removed)
+		}
+
+		str := parent[key].(string)
+		keep := max(len(str)-(len(line)-o.maxLine)-len(truncationSuffix), 0)
+		for keep > 0 && !utf8.RuneStart(str[keep]) {
+			keep--
+		}
+
+		parent[key] = str[:keep] + truncationSuffix
+		if !slices.Contains(removed, path) {
+			removed = append(removed, path)
+		}
+		m["@truncated"] = removed
+
+		next, ok, err := encodeMap(m, meta, o)
+		if !ok {
+			return line, nil
+		}
+		if line = next; err != nil {
+			return nil, err
+		}
+		if len(line) <= o.maxLine {
+			return line, nil
+		}
+	}
+}
+
+// longestString finds the longest string field within nested maps that can
+// still be shortened, returning the map that holds it, its key in that map, and
+// its dot-notation key. The map is nil if there is no such field.
+func longestString(m map[string]any, prefix string) (map[string]any, string, string) {
+	var parent map[string]any
+	var key, path string
+	size := len(truncationSuffix)
+
+	for _, k := range sortedKeys(m) {
+		if prefix == "" && k == "@truncated" {
+			continue
+		}
+
+		switch v := m[k].(type) {
+		case string:
+			if len(v) > size {
+				parent, key, path, size = m, k, prefix+k, len(v)
+			}
+		case map[string]any:
+			if p, nk, np := longestString(v, prefix+k+"."); p != nil && len(p[nk].(string)) > size {
+				parent, key, path, size = p, nk, np, len(p[nk].(string))
+			}
+		}
+	}
+
+	return parent, key, path
+}
+
+// splitLine splits the entry's fields across as many lines as necessary to
+// keep each line within the configured maximum size. Each line is marked with
+// its "@part" number, starting at 1, and the total number of "@parts". A field
+// that does not fit on a line by itself is printed on a line of its own.
+func splitLine(line []byte, m map[string]any, meta Metadata, o option) ([]byte, error) {
+	var parts []map[string]any
+	current := map[string]any{}
+
+	for _, key := range sortedKeys(m) {
+		current[key] = m[key]
+		current["@part"], current["@parts"] = len(parts)+1, len(parts)+1
+
+		next, ok, err := encodeMap(current, meta, o)
+		if !ok {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(next) > o.maxLine && len(current) > 3 {
+			delete(current, key)
+			parts = append(parts, current)
+			current = map[string]any{key: m[key]}
+		}
+	}
+	parts = append(parts, current)
+
+	var lines []byte
+	for i, part := range parts {
+		part["@part"], part["@parts"] = i+1, len(parts)
+
+		next, ok, err := encodeMap(part, meta, o)
+		if !ok {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, next...)
+	}
+
+	return lines, nil
+}
+
+// largestField finds the key of the field with the largest JSON encoding,
+// ignoring the "@truncated" marker.
+func largestField(m map[string]any) string {
+	largest, size := "", -1
+	for k, v := range m {
+		if k == "@truncated" {
+			continue
+		}
+
+		data, _ := json.Marshal(v)
+		if n := len(k) + len(data); n > size || (n == size && k < largest) {
+			largest, size = k, n
+		}
+	}
+
+	return largest
+}
@@ -1,6 +1,3 @@
-//go:build !structuredlogs
-// +build !structuredlogs
-
 /*
 Package logs offers structured, context-based logging via two methods. The
 freeform method lets you create log entries by adding key-value pairs to a
@@ -11,31 +8,28 @@ If you want more control or enforcement over the structure of your log entries,
 you can define a custom type for your logs. The [Logger]'s methods uses this
 type for log entry manipulation. This will require you to pass the [Logger]
 around in your application, but the [Logger.Set] and [Get] functions can help
-make that easier.
+make that easier. Once the [Logger] is in the context, the [Logger] returned
+by [For] can be used anywhere to work with log entries of your custom type,
+much like the freeform method.
 
-Alternatively, build your application with the "structuredlogs" tag. This
-exposes package-level generic functions, similar to the freeform method, but
-using your custom type.
+An application can use the freeform method for some log entries and a custom
+type for others.
 */
 package logs
 
 import (
-	"bytes"
 	"context"
-	"io"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"reflect"
 	"strings"
 	"time"
 )
 
-// FreeformEntry is a freeform log entry.
-type FreeformEntry map[string]any
-
-func newFreeformEntry() *FreeformEntry { return &FreeformEntry{} }
-
 // AddEntry adds a log entry to the context.
 func AddEntry(ctx context.Context, opts ...Option) context.Context {
-	return addEntry(ctx, newFreeformEntry, opts...)
+	return FreeformMode().AddEntry(ctx, opts...)
 }
 
 // Print prints the log entry in the context as JSON. The function will return
@@ -56,238 +50,679 @@ func AddEntry(ctx context.Context, opts ...Option) context.Context {
 // marshal to JSON using the standard json.Marshal(), the function will write an
 // error message to os.Stderr and return false.
 func Print(ctx context.Context, opts ...PrintOption) bool {
-	return print[FreeformEntry](ctx, opts...)
+	return FreeformMode().Print(ctx, opts...)
+}
+
+// PrintE prints the log entry in the context, like [Print], but returns an
+// error describing why the log entry was not printed: [ErrNoEntry] if no log
+// entry is found, an error wrapping [ErrNotPrinted] if it was intentionally not
+// printed, or an error describing a failure to marshal or write it.
+func PrintE(ctx context.Context, opts ...PrintOption) error {
+	return FreeformMode().PrintE(ctx, opts...)
+}
+
+// Finalize prints the log entry in the context, unless it has already been
+// printed, and marks it as finalized. Any later changes to a finalized log
+// entry using functions like [Add] or [Adjust] are ignored, and a diagnostic
+// message is written to os.Stderr. This supports deferring a final print. The
+// function will return false if no log entry is found, or if it was not
+// printed by this call.
+func Finalize(ctx context.Context, opts ...PrintOption) bool {
+	return FreeformMode().Finalize(ctx, opts...)
+}
+
+// Trace sets the log entry's level to TRACE. The function will return false if
+// no log entry is found in the context.
+func Trace(ctx context.Context) bool {
+	return FreeformMode().Trace(ctx)
 }
 
 // Debug sets the log entry's level to DEBUG. The function will return false if
 // no log entry is found in the context.
 func Debug(ctx context.Context) bool {
-	return debug[FreeformEntry](ctx)
+	return FreeformMode().Debug(ctx)
 }
 
 // Info sets the log entry's level to INFO. The function will return false if no
 // log entry is found in the context.
 func Info(ctx context.Context) bool {
-	return info[FreeformEntry](ctx)
+	return FreeformMode().Info(ctx)
 }
 
 // Warn sets the log entry's level to WARN. The function will return false if no
 // log entry is found in the context.
 func Warn(ctx context.Context) bool {
-	return warn[FreeformEntry](ctx)
+	return FreeformMode().Warn(ctx)
 }
 
 // Error sets the log entry's level to ERROR. The function will return false if
 // no log entry is found in the context.
 func Error(ctx context.Context) bool {
-	return err[FreeformEntry](ctx)
+	return FreeformMode().Error(ctx)
 }
 
 // Fatal sets the log entry's level to FATAL. The function will return false if
 // no log entry is found in the context.
 func Fatal(ctx context.Context) bool {
-	return fatal[FreeformEntry](ctx)
+	return FreeformMode().Fatal(ctx)
 }
 
-type keyValue struct {
-	Key   string
-	Value any
+// Suppress prevents the log entry in the context from being printed, such as
+// when a handler recognizes synthetic traffic partway through a request. The
+// [Middleware] honors this, as do all other ways of printing the log entry.
+// Calling [Force] afterwards undoes it. The function will return false if no
+// log entry is found in the context.
+func Suppress(ctx context.Context) bool {
+	return FreeformMode().Suppress(ctx)
 }
 
-func (kv keyValue) adjust(e FreeformEntry, adj func(map[string]any, string)) {
-	split := strings.Split(kv.Key, ".")
+// Force ensures that the log entry in the context is printed regardless of its
+// level or of any sampling, such as when a handler decides that a request
+// needs to be investigated. The [Middleware] honors this, as do all other ways
+// of printing the log entry. Calling [Suppress] afterwards undoes it. The
+// function will return false if no log entry is found in the context.
+func Force(ctx context.Context) bool {
+	return FreeformMode().Force(ctx)
+}
 
-	current := e
-	for i, sub := range split {
-		if i == len(split)-1 {
-			adj(current, sub)
-			break
-		}
+// SetLevel sets the log entry's level to any level, including custom levels
+// created using [RegisterLevel]. The function will return false if no log
+// entry is found in the context.
+func SetLevel(ctx context.Context, level Level) bool {
+	return FreeformMode().SetLevel(ctx, level)
+}
 
-		if _, ok := current[sub]; !ok {
-			current[sub] = FreeformEntry{}
-		}
+// GetFreeformEntry retrieves the freeform log entry from the context. The
+// function will return nil if no freeform log entry is found in the context.
+func GetEntry(ctx context.Context) *FreeformEntry {
+	return FreeformMode().GetEntry(ctx)
+}
 
-		if nested, ok := current[sub].(map[string]any); ok {
-			current = nested
-		} else {
-			m := make(map[string]any)
-			current[sub] = m
-			current = m
-		}
+// Snapshot returns a deep copy of the freeform log entry in the context, as it
+// is at the time of the call. Later changes to the log entry do not affect the
+// copy, and changes to the copy do not affect the log entry, so the copy can be
+// kept for an audit record or passed to an error reporter. The copy is made as
+// described by [Detach]. The function will return false if no freeform log
+// entry is found in the context.
+func Snapshot(ctx context.Context) (FreeformEntry, bool) {
+	if data, ok := snapshot[FreeformEntry](ctx); ok {
+		return *data, true
 	}
+
+	return nil, false
 }
 
-type keyValues []keyValue
+// Merge folds the fields of another freeform log entry into the freeform log
+// entry in the context, such as the log entry of a detached goroutine, whose
+// results should appear in the parent request's log entry. The fields are
+// placed under the key, which may use dot notation, or at the top level of the
+// log entry if the key is empty. Nested maps are merged field by field, and
+// other values replace any that already exist. The other log entry is copied
+// as described by [Detach], so later changes to it have no effect. The
+// function will return false if no freeform log entry is found in the context.
+func Merge(ctx context.Context, other FreeformEntry, underKey string) bool {
+	return merge(ctx, &other, func(into, from *FreeformEntry) {
+		if underKey == "" {
+			mergeFields(*into, *from)
+			return
+		}
 
-func (k keyValues) adjust(e FreeformEntry) {
-	for _, kv := range k {
-		kv.adjust(e, func(m map[string]any, k string) {
-			m[k] = kv.Value
+		kv := keyValue{Key: underKey}
+		kv.adjust(*into, func(m map[string]any, k string) {
+			if dst, ok := asMap(m[k]); ok {
+				mergeFields(dst, *from)
+			} else {
+				m[k] = map[string]any(*from)
+			}
 		})
+	})
+}
+
+// Adjust mutates the log entry in the context. The function will return false
+// if no log entry of the correct type is found in the context.
+func Adjust(ctx context.Context, fns ...Adjuster[FreeformEntry]) bool {
+	return FreeformMode().Adjust(ctx, fns...)
+}
+
+// AdjustE mutates the log entry in the context, like [Adjust], but returns an
+// error describing why the log entry could not be changed: [ErrNoEntry] if no
+// freeform log entry is found, or [ErrFinalized] if it has been finalized.
+func AdjustE(ctx context.Context, fns ...Adjuster[FreeformEntry]) error {
+	return FreeformMode().AdjustE(ctx, fns...)
+}
+
+// Add adds key-value pairs to a freeform log entry. The function will return
+// false if no freeform log entry is found in the context.
+func Add(ctx context.Context, args ...any) bool {
+	return check(AddE(ctx, args...))
+}
+
+// AddE adds key-value pairs to a freeform log entry, like [Add], but returns an
+// error describing why the log entry could not be changed: [ErrNoEntry] if no
+// freeform log entry is found, or [ErrFinalized] if it has been finalized.
+func AddE(ctx context.Context, args ...any) error {
+	e, err := mutableEntry[FreeformEntry](ctx)
+	if err != nil {
+		return err
 	}
+
+	return toKeyValues(args...).adjustWith(*e.data, e.collisions)
 }
 
-func toKeyValues(args ...any) keyValues {
-	kvs := make([]keyValue, len(args)/2)
-	for i := 0; i < len(args); i += 2 {
-		if key, ok := args[i].(string); ok {
-			kvs[i/2] = keyValue{Key: key, Value: args[i+1]}
+// AddLazy adds a key to the freeform log entry in the context whose value is
+// computed by fn only when the log entry is printed, as described by [Lazy].
+// The key may use dot notation to create a nested field. The function will
+// return false if no freeform log entry is found in the context.
+func AddLazy(ctx context.Context, key string, fn func() any) bool {
+	return Add(ctx, key, Lazy(fn))
+}
+
+// AddStruct adds the fields of v to a freeform log entry under the prefix,
+// which may use dot notation. The value is marshaled to JSON, so its json
+// struct tags are respected, and its fields are merged with any that already
+// exist under the prefix. An empty prefix merges the fields into the top level
+// of the log entry. The function will return false if no freeform log entry is
+// found in the context, or if v does not marshal to a JSON object.
+func AddStruct(ctx context.Context, prefix string, v any) bool {
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return false
+	}
+	e := entry.data
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+
+	m, ok := toMap(data)
+	if !ok {
+		return false
+	}
+
+	kvs := make(keyValues, 0, len(m))
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
 		}
+		kvs = append(kvs, keyValue{Key: k, Value: v})
 	}
-	return kvs
+
+	return check(kvs.adjustWith(*e, entry.collisions))
 }
 
-// GetFreeformEntry retrieves the freeform log entry from the context. The
-// function will return nil if no freeform log entry is found in the context.
-func GetEntry(ctx context.Context) *FreeformEntry {
-	if e := getEntry[FreeformEntry](ctx); e != nil {
-		return e.data
-	}
+// AppendMode controls how [AppendWith] handles a key whose existing value is
+// not a []T.
+type AppendMode int
+
+const (
+	// Promote converts the existing value to a []any and appends to it. The
+	// elements of an existing slice are kept, and any other value becomes the
+	// first element. This is the mode used by [Append].
+	Promote AppendMode = iota
+	// Strict leaves the existing value unchanged and appends nothing.
+	Strict
+)
+
+// AppendResult describes what [AppendWith] did to a log entry.
+type AppendResult int
+
+const (
+	// NotAppended means that no values were appended, because no freeform log
+	// entry was found in the context, or because the existing value was not a
+	// []T in [Strict] mode.
+	NotAppended AppendResult = iota
+	// AppendCreated means that the key did not exist, and was created as a []T.
+	AppendCreated
+	// Appended means that the values were appended to an existing []T.
+	Appended
+	// AppendPromoted means that the existing value was not a []T, and was
+	// promoted to a []any before the values were appended.
+	AppendPromoted
+)
+
+// Append adds values to an existing key of the freeform log entry in the
+// context. If the key does not exist, it will be created as a []T. If the key
+// exists but its value is not a []T, such as a []any that has been through a
+// JSON round trip or a slice of another element type, it is promoted to a
+// []any and the values are appended to that. The function will return false
+// if no freeform log entry is found in the context.
+func Append[T any](ctx context.Context, key string, values ...T) bool {
+	return appendValues(ctx, key, Promote, values...) != NotAppended
+}
 
-	return nil
+// AppendWith adds values to a key of the freeform log entry in the context,
+// like [Append], but handles an existing value that is not a []T according to
+// the mode, and reports what it did. Use [Strict] mode to keep a key's
+// element type from changing.
+func AppendWith[T any](ctx context.Context, key string, mode AppendMode, values ...T) AppendResult {
+	return appendValues(ctx, key, mode, values...)
 }
 
-// Adjust mutates the log entry in the context. The function will return false
-// if no log entry of the correct type is found in the context.
-func Adjust(ctx context.Context, fns ...func(*FreeformEntry)) bool {
-	if entry := getEntry[FreeformEntry](ctx); entry != nil {
-		for _, fn := range fns {
-			fn(entry.data)
+// appendValues adds values to a key of the freeform log entry in the context,
+// as described by [AppendWith].
+func appendValues[T any](ctx context.Context, key string, mode AppendMode, values ...T) AppendResult {
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return NotAppended
+	}
+
+	// Appends are made by transports and drivers that may be called
+	// concurrently with the same context.
+	entry.metrics.Lock()
+	defer entry.metrics.Unlock()
+
+	result := NotAppended
+	kv := keyValue{Key: key, Value: values}
+	kv.adjust(*entry.data, func(m map[string]any, k string) {
+		existing, exists := m[k]
+		switch {
+		case !exists || existing == nil:
+			m[k] = values
+			result = AppendCreated
+		case isSliceOf[T](existing):
+			m[k] = append(existing.([]T), values...)
+			result = Appended
+		case mode == Strict:
+		default:
+			m[k] = append(promote(existing), toAny(values)...)
+			result = AppendPromoted
 		}
-		return true
+	})
+
+	return result
+}
+
+func isSliceOf[T any](v any) bool {
+	_, ok := v.([]T)
+	return ok
+}
+
+// promote converts an existing value to a []any. A slice or array keeps its
+// elements, and any other value becomes the only element.
+func promote(v any) []any {
+	if s, ok := v.([]any); ok {
+		return s
 	}
 
-	return false
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []any{v}
+	}
+
+	s := make([]any, rv.Len())
+	for i := range s {
+		s[i] = rv.Index(i).Interface()
+	}
+
+	return s
 }
 
-// Add adds key-value pairs to a freeform log entry. The function will return
-// false if no freeform log entry is found in the context.
-func Add(ctx context.Context, args ...any) bool {
-	if e := GetEntry(ctx); e != nil {
-		toKeyValues(args...).adjust(*e)
+func toAny[T any](values []T) []any {
+	s := make([]any, len(values))
+	for i, v := range values {
+		s[i] = v
+	}
+
+	return s
+}
+
+// GetValue retrieves the value of a key from a freeform log entry, using the
+// same dot notation as [Add] to reach nested fields. The function will return
+// false if no freeform log entry is found in the context, or if the key does
+// not exist.
+func GetValue(ctx context.Context, key string) (any, bool) {
+	entry := getEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return nil, false
+	}
+
+	path := strings.Split(key, ".")
+	parent, ok := nestedMap(*entry.data, path[:len(path)-1])
+	if !ok {
+		return nil, false
+	}
+
+	v, ok := parent[path[len(path)-1]]
+	return v, ok
+}
+
+// Delete removes a key from a freeform log entry, using the same dot notation
+// as [Add] to reach nested fields. The function will return false if no
+// freeform log entry is found in the context, or if the key does not exist.
+func Delete(ctx context.Context, key string) bool {
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return false
+	}
+
+	path := strings.Split(key, ".")
+	parent, ok := nestedMap(*entry.data, path[:len(path)-1])
+	if !ok {
+		return false
+	}
+
+	if _, ok := parent[path[len(path)-1]]; !ok {
+		return false
+	}
+
+	delete(parent, path[len(path)-1])
+	return true
+}
+
+// Msg sets a human-readable message for the freeform log entry in the context,
+// alongside its structured data. The message is written under the "message"
+// key, unless a different key was set using [WithMessageKey] when the log
+// entry was created. Setting the message again replaces it. The function will
+// return false if no freeform log entry is found in the context.
+func Msg(ctx context.Context, msg string) bool {
+	if e := getMutableEntry[FreeformEntry](ctx); e != nil {
+		toKeyValues(e.msgKey, msg).adjust(*e.data)
 		return true
 	}
 
 	return false
 }
 
-// Append adds values to an existing key of the freeform log entry in the
-// context. If the key does not exist, it will be created. The function will
-// return false if no freeform log entry is found in the context, or if the key
-// exists but its value is not []T.
-func Append[T any](ctx context.Context, key string, values ...T) bool {
-	adjusted := false
-
-	adjust(ctx, func(e *FreeformEntry) {
-		kv := keyValue{Key: key, Value: values}
-
-		kv.adjust(*e, func(m map[string]any, k string) {
-			if _, exists := m[k]; !exists {
-				m[k] = values
-				adjusted = true
-			} else if existing, ok := m[k].([]T); ok {
-				m[k] = append(existing, values...)
-				adjusted = true
-			}
-		})
-	})
+// Msgf formats a message according to a format specifier, as fmt.Sprintf
+// does, and sets it as the message of the freeform log entry in the context,
+// like [Msg]. The function will return false if no freeform log entry is found
+// in the context.
+func Msgf(ctx context.Context, format string, args ...any) bool {
+	return Msg(ctx, fmt.Sprintf(format, args...))
+}
 
-	return adjusted
+// AddError writes a description of the error into a freeform log entry under
+// the "@error" key, and sets the log entry's level to ERROR. The description
+// includes the error's message and type, and the message and type of each error
+// in its chain, as found by errors.Unwrap. Use the [WithStack] option to also
+// include a stack trace. The function will return false if no freeform log
+// entry is found in the context, or if the error is nil.
+func AddError(ctx context.Context, err error, opts ...ErrorOption) bool {
+	if err == nil || getMutableEntry[FreeformEntry](ctx) == nil {
+		return false
+	}
+
+	return Add(ctx, "@error", NewErrorData(err, opts...)) && Error(ctx)
 }
 
-// WithBody configures the middleware to write request bodies into each log
-// entry. This option will have no effect unless [Middleware] is operating
-// on a [FreeformEntry].
-func WithBody() MiddlewareOption {
-	return func(o *option) {
-		o.body = true
+// StartTimer starts timing a named operation, such as a database query, and
+// returns a function that stops the timer. When the timer is stopped, the
+// duration of the operation is recorded under the "@timings" key of the
+// freeform log entry. If the same name is timed more than once, the durations
+// are added together. The returned function may be called concurrently, as
+// described by [Count]. If no freeform log entry is found in the context, the
+// returned function does nothing.
+func StartTimer(ctx context.Context, name string) func() {
+	e := getEntry[FreeformEntry](ctx)
+	if e == nil {
+		return func() {}
+	}
+
+	start := e.timer.Now()
+	return func() {
+		elapsed := e.timer.Since(start)
+		if entry := getMutableEntry[FreeformEntry](ctx); entry != nil {
+			entry.metrics.Lock()
+			defer entry.metrics.Unlock()
+
+			timings := timingsOf(*entry.data)
+			if total, ok := timings[name].(time.Duration); ok {
+				elapsed += total
+			}
+			timings[name] = elapsed
+		}
 	}
 }
 
-// WithAllHeaders configures the middleware to write all request headers into
-// each log entry. This option will have no effect unless [Middleware] is
-// operating on a [FreeformEntry].
-func WithAllHeaders() MiddlewareOption {
-	return func(o *option) {
-		o.allHeaders = true
+// Checkpoint records the time elapsed since the freeform log entry was created
+// under the "@timings" key, using the name to mark a point in handling the
+// request, such as "cache_loaded". The function will return false if no
+// freeform log entry is found in the context.
+func Checkpoint(ctx context.Context, name string) bool {
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return false
 	}
+
+	entry.metrics.Lock()
+	defer entry.metrics.Unlock()
+
+	timingsOf(*entry.data)[name] = entry.timer.Since(entry.start)
+	return true
 }
 
-// WithHeaders configures the middleware to write specific request headers into
-// each log entry. This option will have no effect unless [Middleware] is
-// operating on a [FreeformEntry].
-func WithHeaders(headers ...string) MiddlewareOption {
-	return func(o *option) {
-		o.someHeaders = headers
+// timingsOf finds the map of timings in a freeform log entry, creating it if
+// necessary.
+func timingsOf(e FreeformEntry) map[string]any {
+	timings, ok := e["@timings"].(map[string]any)
+	if !ok {
+		timings = make(map[string]any)
+		e["@timings"] = timings
 	}
+
+	return timings
+}
+
+// Count adds delta to a named counter, such as "cache_hits", under the
+// "@metrics" key of the freeform log entry. The counter starts at zero. The
+// function will return false if no freeform log entry is found in the context.
+//
+// Calls to Count, [Max], [Incr], [Decr], [AddFloat], [Append] and
+// [Checkpoint], and to the functions returned by [StartTimer], for the same log
+// entry may be made concurrently with each other, but not with functions that
+// change or print the log entry in other ways, such as [Add] or [Print].
+func Count(ctx context.Context, name string, delta int) bool {
+	return aggregate(ctx, name, func(current int, exists bool) int {
+		return current + delta
+	})
+}
+
+// Incr adds one to the number at the key of the freeform log entry, such as
+// "retries", creating the key with a value of 1 if it does not exist. The key
+// may use dot notation to reach a nested field. It may be called concurrently
+// as described by [Count]. The function will return false if no freeform log
+// entry is found in the context, or if the key holds a value that is not a
+// number.
+func Incr(ctx context.Context, key string) bool {
+	return increment(ctx, key, 1, true)
 }
 
-// HttpData is the data structure for HTTP data that the middleware will apply
-// to log entries under the `@http` key of a [FreeformEntry].
-type HttpData struct {
-	Method   string            `json:"method"`
-	Path     string            `json:"path"`
-	Headers  map[string]string `json:"headers,omitempty"`
-	Body     string            `json:"body,omitempty"`
-	Duration time.Duration     `json:"duration"`
+// Decr subtracts one from the number at the key of the freeform log entry, as
+// described by [Incr]. A key that does not exist is created with a value of -1.
+func Decr(ctx context.Context, key string) bool {
+	return increment(ctx, key, -1, true)
 }
 
-type bodyWatcher struct {
-	io.ReadCloser
-	buf *bytes.Buffer
+// AddFloat adds delta to the number at the key of the freeform log entry, as
+// described by [Incr]. A key that does not exist is created with a value of
+// delta. An integer value becomes a float64 once delta is added to it.
+func AddFloat(ctx context.Context, key string, delta float64) bool {
+	return increment(ctx, key, delta, false)
 }
 
-func (bw *bodyWatcher) Read(p []byte) (int, error) {
-	n, err := bw.ReadCloser.Read(p)
-	if n > 0 {
-		bw.buf.Write(p[:n])
+// Max records n under the "@metrics" key of the freeform log entry, using the
+// name, if n is larger than the value already recorded for the name. It may be
+// called concurrently as described by [Count]. The function will return false
+// if no freeform log entry is found in the context.
+func Max(ctx context.Context, name string, n int) bool {
+	return aggregate(ctx, name, func(current int, exists bool) int {
+		if exists && current >= n {
+			return current
+		}
+		return n
+	})
+}
+
+// EMF records a metric in the freeform log entry in the context, using the
+// CloudWatch Embedded Metric Format, so that CloudWatch extracts it from the
+// printed log line. The value is written under the metric's name, and the
+// dimensions, given as name-value pairs, are written as fields of the log
+// entry. When the log entry is printed, the "_aws" metadata that declares the
+// metric in the namespace is added. Recording the same metric again adds
+// another value. An empty unit is written as "None". The function will return
+// false if no freeform log entry is found in the context.
+func EMF(ctx context.Context, namespace, metricName string, value float64, unit string, dimensions ...string) bool {
+	return recordEMF(ctx, namespace, metricName, value, unit, dimensions...)
+}
+
+// aggregate updates a named value under the "@metrics" key of the freeform log
+// entry in the context, holding the log entry's lock so that concurrent
+// updates are not lost.
+func aggregate(ctx context.Context, name string, fn func(current int, exists bool) int) bool {
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return false
+	}
+
+	entry.metrics.Lock()
+	defer entry.metrics.Unlock()
+
+	metrics, ok := (*entry.data)["@metrics"].(map[string]any)
+	if !ok {
+		metrics = make(map[string]any)
+		(*entry.data)["@metrics"] = metrics
 	}
-	return n, err
+
+	current, exists := metrics[name].(int)
+	metrics[name] = fn(current, exists)
+	return true
 }
 
-// Middleware adds structured, context-based logging to an HTTP handler.
-func Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
-	opt := applyOptions(opts...)
+// increment adds delta to the number at a key of the freeform log entry in the
+// context, holding the log entry's lock so that concurrent updates are not
+// lost. If whole is true, delta is an integer, and integer values stay
+// integers.
+func increment(ctx context.Context, key string, delta float64, whole bool) bool {
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return false
+	}
+
+	entry.metrics.Lock()
+	defer entry.metrics.Unlock()
 
-	var options = func(o *option) {
-		*o = opt
+	updated := false
+	kv := keyValue{Key: key}
+	kv.adjust(*entry.data, func(m map[string]any, k string) {
+		var sum any
+		if sum, updated = addNumber(m[k], delta, whole); updated {
+			m[k] = sum
+		}
+	})
+
+	return updated
+}
+
+// addNumber adds delta to a number, keeping its type where possible. A nil
+// value is treated as zero. The function will return false if the value is
+// not a number.
+func addNumber(current any, delta float64, whole bool) (any, bool) {
+	switch c := current.(type) {
+	case nil:
+		if whole {
+			return int(delta), true
+		}
+		return delta, true
+	case json.Number:
+		if i, err := c.Int64(); err == nil && whole {
+			return i + int64(delta), true
+		}
+		f, err := c.Float64()
+		return f + delta, err == nil
 	}
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := opt.timer.Now()
+	v := reflect.ValueOf(current)
+	sum := reflect.New(v.Type()).Elem()
+	switch {
+	case v.CanInt() && whole:
+		sum.SetInt(v.Int() + int64(delta))
+	case v.CanUint() && whole && (delta >= 0 || v.Uint() >= uint64(-delta)):
+		sum.SetUint(v.Uint() + uint64(delta))
+	case v.CanUint() && whole:
+		return int64(v.Uint()) + int64(delta), true
+	case v.CanInt():
+		return float64(v.Int()) + delta, true
+	case v.CanUint():
+		return float64(v.Uint()) + delta, true
+	case v.CanFloat():
+		sum.SetFloat(v.Float() + delta)
+	default:
+		return nil, false
+	}
 
-			ctx := AddEntry(r.Context(), options)
-			data := HttpData{Method: r.Method, Path: r.URL.Path}
+	return sum.Interface(), true
+}
 
-			var buf *bytes.Buffer
-			if opt.body {
-				buf = new(bytes.Buffer)
-				r.Body = &bodyWatcher{r.Body, buf}
-			}
+// With adds key-value pairs to the freeform log entry in the context for the
+// duration of fn. Once fn returns, the keys that were added are removed, and
+// any values that they replaced are restored. The function will return false if
+// no freeform log entry is found in the context, in which case fn is still
+// called.
+func With(ctx context.Context, fn func(ctx context.Context), args ...any) bool {
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		fn(ctx)
+		return false
+	}
+	e := entry.data
 
-			next.ServeHTTP(w, r.WithContext(ctx))
+	kvs := toKeyValues(args...)
+	scopes := make([]scopedValue, len(kvs))
+	for i, kv := range kvs {
+		scopes[i] = newScopedValue(*e, kv.Key)
+		keyValues{kv}.adjust(*e)
+	}
 
-			data.Duration = opt.timer.Since(start)
-			if opt.body {
-				data.Body = buf.String()
-			}
+	defer func() {
+		for i := len(scopes) - 1; i >= 0; i-- {
+			scopes[i].restore(*e)
+		}
+	}()
 
-			if len(opt.someHeaders) > 0 {
-				data.Headers = make(map[string]string)
-				for _, h := range opt.someHeaders {
-					data.Headers[h] = r.Header.Get(h)
-				}
-			} else if opt.allHeaders {
-				data.Headers = make(map[string]string)
-				for k := range r.Header {
-					data.Headers[k] = r.Header.Get(k)
-				}
-			}
+	fn(ctx)
+	return true
+}
 
-			Add(ctx, "@http", data)
-			Print(ctx, options)
-		})
+// AddChild adds a child log entry to the context, scoped to a region of code
+// such as a single downstream call. Functions like [Add] and [Error] that use
+// the returned context operate on the child. The returned function ends the
+// scope, merging the child's fields under the key of the parent log entry, and
+// raising the parent's level if the child's level was set higher. This lets a
+// single log line hold a structured section for each operation. If there is no
+// freeform log entry in the context, the context is returned unchanged and the
+// returned function does nothing.
+func AddChild(ctx context.Context, key string, opts ...Option) (context.Context, func()) {
+	parent := getEntry[FreeformEntry](ctx)
+	if parent == nil {
+		return ctx, func() {}
+	}
+
+	child := addEntry(ctx, newFreeformEntry, opts...)
+
+	return child, func() {
+		e := getEntry[FreeformEntry](child)
+		if getMutableEntry[FreeformEntry](ctx) == nil {
+			return
+		}
+
+		kvs := make(keyValues, 0, len(*e.data))
+		for k, v := range *e.data {
+			kvs = append(kvs, keyValue{Key: key + "." + k, Value: v})
+		}
+		check(kvs.adjustWith(*parent.data, parent.collisions))
+
+		if e.leveled && e.level > parent.currentLevel() {
+			parent.setLevel(e.level)
+		}
 	}
 }
+
+// Middleware adds structured, context-based logging to an HTTP handler.
+//
+// If the [WithLayerTiming] option is used and the request's context already
+// contains a log entry, the middleware records its timing into that entry
+// rather than creating and printing a new one.
+func Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	return FreeformMode().Middleware(opts...)
+}
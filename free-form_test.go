@@ -1,15 +1,27 @@
-//go:build !structuredlogs
-// +build !structuredlogs
-
 package logs_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync"
+	"testing"
 	"time"
 
 	"github.com/rclark/logs"
@@ -55,6 +67,24 @@ func ExampleAppend() {
 	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","messages":["hello","world","goodbye"]}
 }
 
+func ExampleAppendWith() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"ids", []int{1, 2},
+		"tags", []string{"a"},
+	)
+
+	fmt.Println(logs.AppendWith(ctx, "ids", logs.Promote, "three") == logs.AppendPromoted)
+	fmt.Println(logs.AppendWith(ctx, "tags", logs.Strict, 42) == logs.NotAppended)
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// true
+	// true
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","ids":[1,2,"three"],"tags":["a"]}
+}
+
 func ExampleAdjust() {
 	ctx := logs.AddEntry(context.Background())
 
@@ -141,7 +171,58 @@ func ExampleMiddleware() {
 	if w.Code != http.StatusOK {
 		log.Fatal("unexpected status code")
 	}
-	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","duration":1234},"foo":"bar","messages":["hello","world"]}
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","status":200,"response_bytes":0,"duration":1234},"foo":"bar","messages":["hello","world"]}
+}
+
+func ExampleWithClientInfo() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithClientInfo(),
+		logs.WithTrustedProxies("192.0.2.0/24", "10.0.0.0/8"),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.1.2.3")
+	r.Header.Set("User-Agent", "curl/8.5.0")
+	r.Header.Set("Referer", "https://example.com/")
+
+	middleware(freeformHandler).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","remote_addr":"192.0.2.1:1234","client_ip":"203.0.113.7","user_agent":"curl/8.5.0","referer":"https://example.com/","status":200,"response_bytes":0,"duration":1234},"foo":"","messages":["hello","world"]}
+}
+
+func ExampleWithConnectionInfo() {
+	middleware := logs.Middleware(logs.WithTiming(time.Time{}, time.Duration(1234)), logs.WithConnectionInfo())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "https://api.example.com/path", nil)
+	r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/2.0", 2, 0
+	r.TLS = &tls.ConnectionState{
+		Version:            tls.VersionTLS13,
+		CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+		ServerName:         "api.example.com",
+		NegotiatedProtocol: "h2",
+	}
+
+	middleware(freeformHandler).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","conn":{"protocol":"HTTP/2.0","tls_version":"TLS 1.3","cipher_suite":"TLS_AES_128_GCM_SHA256","server_name":"api.example.com","alpn":"h2"},"status":200,"response_bytes":0,"duration":1234},"foo":"","messages":["hello","world"]}
+}
+
+func ExampleWithRateLimit() {
+	limiter := logs.NewRateLimiter(2, time.Minute)
+
+	for i := 1; i <= 4; i++ {
+		ctx := logs.AddEntry(context.Background())
+		logs.Add(ctx, "attempt", i)
+		logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithRateLimit(limiter))
+	}
+
+	limiter.Flush()
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","attempt":1}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","attempt":2}
+	// {"@level":"WARN","@time":"0001-01-01T00:00:00Z","@rate_limit":{"dropped":2}}
 }
 
 func ExampleMiddleware_withBody() {
@@ -154,7 +235,7 @@ func ExampleMiddleware_withBody() {
 	if w.Code != http.StatusOK {
 		log.Fatal("unexpected status code")
 	}
-	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","body":"bar","duration":1234},"foo":"bar","messages":["hello","world"]}
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","body":"bar","status":200,"response_bytes":0,"duration":1234},"foo":"bar","messages":["hello","world"]}
 }
 
 func ExampleMiddleware_someHeaders() {
@@ -169,7 +250,7 @@ func ExampleMiddleware_someHeaders() {
 	if w.Code != http.StatusOK {
 		log.Fatal("unexpected status code")
 	}
-	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","headers":{"X-Header":"x"},"duration":1234},"foo":"bar","messages":["hello","world"]}
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","headers":{"X-Header":"x"},"status":200,"response_bytes":0,"duration":1234},"foo":"bar","messages":["hello","world"]}
 }
 
 func ExampleMiddleware_allHeaders() {
@@ -184,5 +265,1886 @@ func ExampleMiddleware_allHeaders() {
 	if w.Code != http.StatusOK {
 		log.Fatal("unexpected status code")
 	}
-	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","headers":{"X-Header":"x","Y-Header":"y"},"duration":1234},"foo":"bar","messages":["hello","world"]}
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","headers":{"X-Header":"x","Y-Header":"y"},"status":200,"response_bytes":0,"duration":1234},"foo":"bar","messages":["hello","world"]}
+}
+
+func ExampleWithFieldAllowlist() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"name", "test",
+		"count", 42,
+		"user.id", 1234,
+		"user.email", "test@example.com",
+	)
+
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithFieldAllowlist("name", "user.id", "missing"),
+	)
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","user":{"id":1234}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","count":42,"name":"test","user":{"email":"test@example.com","id":1234}}
+}
+
+func ExampleWithLayerTiming() {
+	timing := logs.WithTiming(time.Time{}, time.Duration(1234))
+	outer := logs.Middleware(timing, logs.WithLayerTiming("outer"))
+	inner := logs.Middleware(timing, logs.WithLayerTiming("inner"))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("bar"))
+
+	outer(inner(freeformHandler)).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		log.Fatal("unexpected status code")
+	}
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","status":200,"response_bytes":0,"duration":1234},"@timing":{"inner":1234,"outer":1234},"foo":"bar","messages":["hello","world"]}
+}
+
+func ExampleWith() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"name", "test",
+		"user.id", 1234,
+	)
+
+	logs.With(ctx, func(ctx context.Context) {
+		logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	},
+		"name", "scoped",
+		"user.email", "test@example.com",
+		"request.attempt", 2,
+	)
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"scoped","request":{"attempt":2},"user":{"email":"test@example.com","id":1234}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","user":{"id":1234}}
+}
+
+func ExampleWithBodyEncoding() {
+	body := []byte{0x00, 0xff, 0x10, 0x80}
+
+	for _, encoding := range []logs.BodyEncoding{logs.BodyBase64, logs.BodyHex} {
+		middleware := logs.Middleware(
+			logs.WithTiming(time.Time{}, time.Duration(1234)),
+			logs.WithBody(),
+			logs.WithBodyEncoding(encoding),
+		)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/path", bytes.NewReader(body))
+
+		middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+		})).ServeHTTP(w, r)
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","body":"AP8QgA==","status":200,"response_bytes":0,"duration":1234}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","body":"00ff1080","status":200,"response_bytes":0,"duration":1234}}
+}
+
+func ExampleWithAttemptHeader() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithAttemptHeader("X-Retry-Count"),
+	)
+
+	for _, value := range []string{"3", "", "three"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/path", nil)
+		if value != "" {
+			r.Header.Set("X-Retry-Count", value)
+		}
+
+		middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","status":200,"response_bytes":0,"attempt":3,"duration":1234}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","status":200,"response_bytes":0,"attempt":0,"duration":1234}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","status":200,"response_bytes":0,"attempt":0,"duration":1234}}
+}
+
+type manualTimer struct {
+	now time.Time
+}
+
+func (t *manualTimer) Now() time.Time {
+	return t.now
+}
+
+func (t *manualTimer) Since(start time.Time) time.Duration {
+	return t.now.Sub(start)
+}
+
+func (t *manualTimer) Advance(d time.Duration) {
+	t.now = t.now.Add(d)
+}
+
+func ExampleWithTTFB() {
+	timer := &manualTimer{}
+
+	middleware := logs.Middleware(logs.WithTimer(timer), logs.WithTTFB())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timer.Advance(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		timer.Advance(50 * time.Millisecond)
+		_, _ = w.Write([]byte("hello"))
+	})).ServeHTTP(w, r)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/path", nil)
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timer.Advance(100 * time.Millisecond)
+	})).ServeHTTP(w, r)
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","status":200,"response_bytes":5,"ttfb":100000000,"duration":150000000}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","status":200,"response_bytes":0,"duration":100000000}}
+}
+
+func ExampleWithDualOutput() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"name", "test",
+		"greeting", "hello world",
+		"user.id", 1234,
+		"messages", []string{"hello", "world"},
+	)
+
+	var human, machine bytes.Buffer
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithDualOutput(&human, &machine),
+	)
+
+	fmt.Print(human.String())
+	fmt.Println(json.Valid(machine.Bytes()))
+	fmt.Print(machine.String())
+	// Output:
+	// 0001-01-01T00:00:00Z INFO greeting="hello world" messages=["hello","world"] name=test user.id=1234
+	// true
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","greeting":"hello world","messages":["hello","world"],"name":"test","user":{"id":1234}}
+}
+
+func ExampleAddStruct() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx, "config.env", "test")
+
+	logs.AddStruct(ctx, "config", logs.ExampleLog{
+		Name:  "test",
+		Count: 42,
+	})
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","config":{"count":42,"env":"test","flag":false,"name":"test"}}
+}
+
+func ExampleWithHandlerName() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithHandlerName("freeformHandler"),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("bar"))
+
+	middleware(freeformHandler).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","handler":"freeformHandler","status":200,"response_bytes":0,"duration":1234},"foo":"bar","messages":["hello","world"]}
+}
+
+func ExampleWithMaxLineBytes() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"name", "test",
+		"count", 42,
+		"body", strings.Repeat("x", 100),
+	)
+
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithMaxLineBytes(100),
+	)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@truncated":true,"count":42,"name":"test"}
+}
+
+func ExampleWithTenantRouter() {
+	var a, b, other bytes.Buffer
+
+	tenant := func(ctx context.Context) string {
+		if e := logs.GetEntry(ctx); e != nil {
+			if id, ok := (*e)["tenant"].(string); ok {
+				return id
+			}
+		}
+		return ""
+	}
+
+	printOptions := []logs.PrintOption{
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithTenantRouter(tenant, map[string]io.Writer{"a": &a, "b": &b}, &other),
+	}
+
+	for _, id := range []string{"a", "b", "c", "a"} {
+		ctx := logs.AddEntry(context.Background())
+		logs.Add(ctx, "tenant", id)
+		logs.Print(ctx, printOptions...)
+	}
+
+	fmt.Print("a: ", a.String())
+	fmt.Print("b: ", b.String())
+	fmt.Print("other: ", other.String())
+	// Output:
+	// a: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","tenant":"a"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","tenant":"a"}
+	// b: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","tenant":"b"}
+	// other: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","tenant":"c"}
+}
+
+func ExampleWithFieldCount() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithFieldCount())
+
+	logs.Add(ctx,
+		"name", "test",
+		"user.id", 1234,
+		"user.email", "test@example.com",
+	)
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithFieldCount())
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@field_count":0}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@field_count":2,"name":"test","user":{"email":"test@example.com","id":1234}}
+}
+
+func ExampleFinalize() {
+	ctx := logs.AddEntry(context.Background())
+
+	func() {
+		defer logs.Finalize(ctx, logs.WithCurrentTime(time.Time{}))
+		logs.Add(ctx, "name", "test")
+	}()
+
+	fmt.Println(logs.Finalize(ctx, logs.WithCurrentTime(time.Time{})))
+	fmt.Println(logs.Add(ctx, "count", 42))
+	fmt.Println(logs.GetEntry(ctx))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test"}
+	// false
+	// false
+	// &map[name:test]
+}
+
+func ExampleWithEMF() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"route", "/path",
+		"latency", 125,
+		"bytes", 2048,
+	)
+
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Unix(1700000000, 0).UTC()),
+		logs.WithEMF("my-service", "latency:Milliseconds", "bytes:Bytes", "missing"),
+	)
+	// Output: {"@level":"INFO","@time":"2023-11-14T22:13:20Z","_aws":{"CloudWatchMetrics":[{"Dimensions":[[]],"Metrics":[{"Name":"latency","Unit":"Milliseconds"},{"Name":"bytes","Unit":"Bytes"}],"Namespace":"my-service"}],"Timestamp":1700000000000},"bytes":2048,"latency":125,"route":"/path"}
+}
+
+func ExampleEMF() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "route", "/path")
+
+	logs.EMF(ctx, "my-service", "latency", 125, "Milliseconds", "Operation", "GetUser")
+	logs.EMF(ctx, "my-service", "latency", 80, "Milliseconds", "Operation", "GetUser")
+	logs.EMF(ctx, "my-service", "cache_hits", 3, "Count", "Operation", "GetUser")
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Unix(1700000000, 0).UTC()))
+	// Output: {"@level":"INFO","@time":"2023-11-14T22:13:20Z","Operation":"GetUser","_aws":{"CloudWatchMetrics":[{"Dimensions":[["Operation"]],"Metrics":[{"Name":"latency","Unit":"Milliseconds"},{"Name":"cache_hits","Unit":"Count"}],"Namespace":"my-service"}],"Timestamp":1700000000000},"cache_hits":3,"latency":[125,80],"route":"/path"}
+}
+
+func ExampleWithEagerBody() {
+	middleware := logs.Middleware(logs.WithTiming(time.Time{}, time.Duration(1234)), logs.WithEagerBody())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("bar"))
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "ignored", "body")
+	})).ServeHTTP(w, r)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("bar"))
+
+	middleware(freeformHandler).ServeHTTP(w, r)
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","body":"bar","status":200,"response_bytes":0,"duration":1234},"ignored":"body"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","body":"bar","status":200,"response_bytes":0,"duration":1234},"foo":"bar","messages":["hello","world"]}
+}
+
+func ExampleWithBodyLimit() {
+	for _, body := range []logs.MiddlewareOption{logs.WithBody(), logs.WithEagerBody()} {
+		middleware := logs.Middleware(
+			logs.WithTiming(time.Time{}, time.Duration(1234)),
+			body,
+			logs.WithBodyLimit(5),
+		)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("hello world"))
+
+		middleware(freeformHandler).ServeHTTP(w, r)
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","body":"hello","body_bytes":11,"body_truncated":true,"status":200,"response_bytes":0,"duration":1234},"foo":"hello world","messages":["hello","world"]}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","body":"hello","body_bytes":11,"body_truncated":true,"status":200,"response_bytes":0,"duration":1234},"foo":"hello world","messages":["hello","world"]}
+}
+
+func ExampleWithBodySkipTypes() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithBody(),
+		logs.WithBodySkipTypes("image/*", "multipart/form-data"),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("bar"))
+	r.Header.Set("Content-Type", "image/png")
+
+	middleware(freeformHandler).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"POST","path":"/path","status":200,"response_bytes":0,"duration":1234},"foo":"bar","messages":["hello","world"]}
+}
+
+func ExampleWithResponseBody() {
+	middleware := logs.Middleware(logs.WithTiming(time.Time{}, time.Duration(1234)), logs.WithResponseBody(16))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"missing parameter \"id\""}`, http.StatusBadRequest)
+	})).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","status":400,"response_bytes":37,"response_body":"{\"error\":\"missin","duration":1234}}
+}
+
+func ExampleWithRoutePattern() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "user", r.PathValue("id"))
+	})
+
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithQuery(),
+		logs.WithRoutePattern(func(r *http.Request) string {
+			_, pattern := mux.Handler(r)
+			return pattern
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users/42?fields=name&fields=email", nil)
+
+	middleware(mux).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/users/42","route":"GET /users/{id}","query":{"fields":["name","email"]},"status":200,"response_bytes":0,"duration":1234},"user":"42"}
+}
+
+func ExampleWithResponseHeaders() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithResponseHeaders("Content-Type"),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	})).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","status":404,"response_bytes":9,"response_headers":{"Content-Type":"text/plain"},"duration":1234}}
+}
+
+func ExampleSlogHandler() {
+	logger := slog.New(logs.NewSlogHandler(slog.LevelInfo))
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "name", "test")
+
+	logger.InfoContext(ctx, "started", "attempt", 1)
+	logger.DebugContext(ctx, "ignored", "debug", true)
+	logger.
+		WithGroup("db").
+		With("table", "users").
+		WarnContext(ctx, "slow query", slog.Group("query", "rows", 42))
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"WARN","@time":"0001-01-01T00:00:00Z","attempt":1,"db":{"query":{"rows":42},"table":"users"},"messages":["started","slow query"],"name":"test"}
+}
+
+func ExampleWithRecovery() {
+	var buf bytes.Buffer
+
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithRecovery(),
+		logs.Output(&buf),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "name", "test")
+		panic("something went wrong")
+	})).ServeHTTP(w, r)
+
+	var entry struct {
+		Level string         `json:"@level"`
+		HTTP  logs.HttpData  `json:"@http"`
+		Panic logs.PanicData `json:"@panic"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(w.Code)
+	fmt.Println(entry.Level, entry.HTTP.Status)
+	fmt.Println(entry.Panic.Value)
+	fmt.Println(strings.Contains(entry.Panic.Stack, "ExampleWithRecovery"))
+	// Output:
+	// 500
+	// ERROR 500
+	// something went wrong
+	// true
+}
+
+func ExampleAddChild() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "route", "/checkout")
+
+	for _, call := range []string{"inventory", "payment"} {
+		child, done := logs.AddChild(ctx, "calls."+call)
+		logs.Add(child, "attempts", 1)
+		if call == "payment" {
+			logs.Add(child, "error", "card declined")
+			logs.Error(child)
+		}
+		done()
+	}
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","calls":{"inventory":{"attempts":1},"payment":{"attempts":1,"error":"card declined"}},"route":"/checkout"}
+}
+
+func ExampleAddChild_collisionPolicy() {
+	ctx := logs.AddEntry(context.Background(), logs.WithCollisionPolicy(logs.CollisionRename))
+	logs.Add(ctx, "calls", "none")
+
+	child, done := logs.AddChild(ctx, "calls.inventory")
+	logs.Add(child, "attempts", 1)
+	done()
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","calls":{"inventory":{"attempts":1}},"calls_1":"none"}
+}
+
+func ExampleWithRedaction() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"user.name", "test",
+		"user.password", "hunter2",
+		"headers.Authorization", "Bearer abc123",
+		"note", "paid with 4111 1111 1111 1111",
+	)
+
+	card := regexp.MustCompile(`\b(?:\d[ -]?){12,15}\d\b`)
+
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithRedaction("password", "authorization"),
+		logs.WithRedactor(func(key string, value any) (any, bool) {
+			if s, ok := value.(string); ok && card.MatchString(s) {
+				return card.ReplaceAllString(s, logs.Redacted), true
+			}
+			return nil, false
+		}),
+	)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","headers":{"Authorization":"[REDACTED]"},"note":"paid with [REDACTED]","user":{"name":"test","password":"[REDACTED]"}}
+}
+
+func ExampleWithRequestID() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithRequestID(),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "request_id", logs.RequestID(r.Context()))
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set("X-Request-Id", "abc123")
+	handler.ServeHTTP(w, r)
+	fmt.Println(w.Header().Get("X-Request-Id"))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","request_id":"abc123","status":200,"response_bytes":0,"duration":1234},"request_id":"abc123"}
+	// abc123
+}
+
+func ExampleGetValue() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"user.name", "test",
+		"user.password", "hunter2",
+	)
+
+	name, ok := logs.GetValue(ctx, "user.name")
+	fmt.Println(name, ok)
+
+	fmt.Println(logs.Delete(ctx, "user.password"))
+	fmt.Println(logs.Delete(ctx, "user.email"))
+
+	_, ok = logs.GetValue(ctx, "user.password")
+	fmt.Println(ok)
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// test true
+	// true
+	// false
+	// false
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","user":{"name":"test"}}
+}
+
+func ExampleWithEncoder() {
+	ctx := logs.AddEntry(context.Background())
+
+	logs.Add(ctx,
+		"name", "test",
+		"user.id", 1234,
+		"message", "hello world",
+	)
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithEncoder(logs.LogfmtEncoder{}))
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithEncoder(logs.ConsoleEncoder{}))
+	// Output:
+	// level=INFO time=0001-01-01T00:00:00Z message="hello world" name=test user.id=1234
+	// 0001-01-01T00:00:00Z INFO message="hello world" name=test user.id=1234
+}
+
+func ExampleAddError() {
+	ctx := logs.AddEntry(context.Background())
+
+	_, err := os.Open("/does/not/exist")
+	logs.AddError(ctx, fmt.Errorf("loading config: %w", err))
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","@error":{"message":"loading config: open /does/not/exist: no such file or directory","type":"*fmt.wrapError","chain":[{"message":"open /does/not/exist: no such file or directory","type":"*fs.PathError"},{"message":"no such file or directory","type":"syscall.Errno"}]}}
+}
+
+func ExampleWithSampler() {
+	healthChecks := func(ctx context.Context, entry any) bool {
+		route, _ := logs.GetValue(ctx, "route")
+		return route != "/health"
+	}
+
+	for _, route := range []string{"/health", "/users", "/health"} {
+		ctx := logs.AddEntry(context.Background())
+		logs.Add(ctx, "route", route)
+		if route == "/health" {
+			logs.Warn(ctx)
+		}
+		logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithSampler(healthChecks))
+	}
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "route", "/health")
+	fmt.Println(logs.Print(ctx, logs.WithSampler(healthChecks)))
+	// Output:
+	// {"@level":"WARN","@time":"0001-01-01T00:00:00Z","route":"/health"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","route":"/users"}
+	// {"@level":"WARN","@time":"0001-01-01T00:00:00Z","route":"/health"}
+	// false
+}
+
+func ExampleSampling() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.Sampling(0),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			logs.Error(r.Context())
+		}
+	}))
+
+	for _, path := range []string{"/ok", "/fail"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Output: {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/fail","status":200,"response_bytes":0,"duration":1234}}
+}
+
+func ExampleSuppress() {
+	middleware := logs.Middleware(logs.WithTiming(time.Time{}, time.Duration(1234)))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") == "synthetic-monitor" {
+			logs.Suppress(r.Context())
+		}
+		logs.Error(r.Context())
+	}))
+
+	for _, agent := range []string{"synthetic-monitor", "curl/8.0"} {
+		r := httptest.NewRequest(http.MethodGet, "/users", nil)
+		r.Header.Set("User-Agent", agent)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	// Output: {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/users","status":200,"response_bytes":0,"duration":1234}}
+}
+
+func ExampleForce() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.PrintLevel(logs.WARN),
+		logs.Sampling(0),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("debug") {
+			logs.Force(r.Context())
+		}
+	}))
+
+	for _, target := range []string{"/users", "/users?debug"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/users","status":200,"response_bytes":0,"duration":1234}}
+}
+
+func ExampleEmitOnErrorOrSlow() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.EmitOnErrorOrSlow(500*time.Millisecond),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "user.id", 42)
+		switch r.URL.Path {
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/invalid":
+			logs.Error(r.Context())
+		}
+	}))
+
+	for _, path := range []string{"/ok", "/unavailable", "/invalid"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/unavailable","status":503,"response_bytes":0,"duration":1234},"user":{"id":42}}
+	// {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/invalid","status":200,"response_bytes":0,"duration":1234},"user":{"id":42}}
+}
+
+func ExampleWithEmitWhen() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithEmitWhen(func(status int, duration time.Duration, entry any) bool {
+			_, retried := (*entry.(*logs.FreeformEntry))["retries"]
+			return status >= 400 || retried
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" {
+			logs.Add(r.Context(), "retries", 2)
+		}
+	}))
+
+	for _, path := range []string{"/ok", "/flaky"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/flaky","status":200,"response_bytes":0,"duration":1234},"retries":2}
+}
+
+func ExampleWithMetadataKeys() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "name", "test")
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC)
+
+	logs.Print(ctx,
+		logs.WithCurrentTime(now),
+		logs.WithMetadataKeys("severity", "timestamp"),
+		logs.WithTimeFormat(time.RFC3339Nano),
+	)
+	logs.Print(ctx,
+		logs.WithCurrentTime(now),
+		logs.WithTimeFormat(logs.TimeUnixMilli),
+	)
+	// Output:
+	// {"severity":"INFO","timestamp":"2024-01-02T03:04:05.6Z","name":"test"}
+	// {"@level":"INFO","@time":1704164645600,"name":"test"}
+}
+
+func ExampleGCPEncoder() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), 1500*time.Millisecond),
+		logs.WithHeaders("User-Agent"),
+		logs.Encoding(logs.GCPEncoder{ProjectID: "my-project"}),
+		logs.ContextFields(func(ctx context.Context) []logs.MetaField {
+			return []logs.MetaField{
+				{Key: "trace_id", Value: "4bf92f3577b34da6a3ce929d0e0e4736"},
+				{Key: "span_id", Value: "00f067aa0ba902b7"},
+				{Key: "trace_flags", Value: "01"},
+			}
+		}),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set("User-Agent", "curl/8.0")
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Warn(r.Context())
+		_, _ = w.Write([]byte("ok"))
+	})).ServeHTTP(w, r)
+	// Output: {"@http":{"headers":{"User-Agent":"curl/8.0"}},"httpRequest":{"latency":"1.5s","requestMethod":"GET","requestUrl":"/path","responseSize":"2","status":200,"userAgent":"curl/8.0"},"logging.googleapis.com/spanId":"00f067aa0ba902b7","logging.googleapis.com/trace":"projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736","logging.googleapis.com/trace_sampled":true,"severity":"WARNING","time":"2024-01-02T03:04:05Z"}
+}
+
+func ExampleGCPEncoder_durationFormat() {
+	for _, style := range []logs.DurationStyle{logs.DurationMillis, logs.DurationString} {
+		middleware := logs.Middleware(
+			logs.WithTiming(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), 1500*time.Millisecond),
+			logs.Encoding(logs.GCPEncoder{}),
+			logs.DurationFormat(style),
+		)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/path", nil)
+
+		middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+	}
+	// Output:
+	// {"httpRequest":{"latency":"1.5s","requestMethod":"GET","requestUrl":"/path","responseSize":"0","status":200},"severity":"INFO","time":"2024-01-02T03:04:05Z"}
+	// {"httpRequest":{"latency":"1.5s","requestMethod":"GET","requestUrl":"/path","responseSize":"0","status":200},"severity":"INFO","time":"2024-01-02T03:04:05Z"}
+}
+
+func ExampleECSEncoder() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "service.name", "api")
+	logs.AddError(ctx, errors.New("connection refused"))
+
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+		logs.WithEncoder(logs.ECSEncoder{}),
+	)
+	// Output: {"@timestamp":"2024-01-02T03:04:05Z","ecs":{"version":"8.11.0"},"error":{"message":"connection refused","type":"*errors.errorString"},"log":{"level":"error"},"service":{"name":"api"}}
+}
+
+func ExampleDatadogEncoder() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), 1500*time.Millisecond),
+		logs.WithHeaders("User-Agent"),
+		logs.Encoding(logs.DatadogEncoder{Service: "api", Env: "prod"}),
+		logs.WithTraceHeaders(),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users?id=42", nil)
+	r.Header.Set("User-Agent", "curl/8.0")
+	r.Header.Set("X-Datadog-Trace-Id", "7277407061855694839")
+	r.Header.Set("X-Datadog-Parent-Id", "5208512171318403364")
+	r.Header.Set("X-Datadog-Sampling-Priority", "1")
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Msg(r.Context(), "user not found")
+		logs.Warn(r.Context())
+		w.WriteHeader(http.StatusNotFound)
+	})).ServeHTTP(w, r)
+	// Output: {"@http":{"headers":{"User-Agent":"curl/8.0"}},"dd":{"env":"prod","service":"api","span_id":"5208512171318403364","trace_id":"7277407061855694839"},"duration":1500000000,"http":{"method":"GET","status_code":404,"url":"/users","url_details":{"path":"/users"},"useragent":"curl/8.0"},"message":"user not found","network":{"bytes_written":0},"status":"warn","timestamp":"2024-01-02T03:04:05Z"}
+}
+
+func ExampleWithHook() {
+	hostname := func(level logs.Level, e *logs.FreeformEntry) error {
+		(*e)["host"] = "web-1"
+		return nil
+	}
+
+	ctx := logs.AddEntry(context.Background(), logs.WithHook(hostname))
+	logs.Add(ctx, "name", "test")
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","host":"web-1","name":"test"}
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	fn()
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out)
+}
+
+func TestWithHook_mismatched(t *testing.T) {
+	hook := func(level logs.Level, e *logs.ExampleLog) error {
+		return nil
+	}
+
+	out := captureStderr(t, func() {
+		logs.AddEntry(context.Background(), logs.WithHook(hook))
+	})
+
+	want := "hook of type func(logs.Level, *logs.ExampleLog) error does not match log entry of type logs.FreeformEntry, ignoring it\n"
+	if out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+}
+
+func TestWithDefaults_mismatched(t *testing.T) {
+	out := captureStderr(t, func() {
+		logs.AddEntry(context.Background(), logs.WithDefaults(func(e *logs.ExampleLog) {
+			e.Name = "test"
+		}))
+	})
+
+	want := "default of type func(*logs.ExampleLog) does not match log entry of type logs.FreeformEntry, ignoring it\n"
+	if out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+}
+
+func TestWithValidator_mismatched(t *testing.T) {
+	validator := func(e *logs.ExampleLog) error {
+		return nil
+	}
+
+	out := captureStderr(t, func() {
+		logs.AddEntry(context.Background(), logs.WithValidator(validator))
+	})
+
+	want := "validator of type logs.Validator[github.com/rclark/logs.ExampleLog] does not match log entry of type logs.FreeformEntry, ignoring it\n"
+	if out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+}
+
+func ExampleFields() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.Fields("service", "api", "version", "1.2.3"),
+	)
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "user.id", 1234)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/path", nil))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/path","status":200,"response_bytes":0,"duration":1234},"service":"api","user":{"id":1234},"version":"1.2.3"}
+}
+
+func ExampleTransport() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: logs.Transport(nil, logs.WithTiming(time.Time{}, time.Duration(1234))),
+	}
+
+	ctx := logs.AddEntry(context.Background())
+	for _, path := range []string{"/users?api_key=secret", "/missing"} {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	out := new(bytes.Buffer)
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOutput(out))
+	fmt.Print(strings.ReplaceAll(out.String(), server.URL, "http://server"))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http_client":[{"method":"GET","url":"http://server/users","status":200,"duration":1234},{"method":"GET","url":"http://server/missing","status":404,"duration":1234}]}
+}
+
+func ExampleWithSkipPaths() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithSkipPaths("/healthz", "/metrics"),
+		logs.WithSkipFunc(func(r *http.Request) bool {
+			return r.Method == http.MethodOptions
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodOptions, "/users", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/users","status":200,"response_bytes":0,"duration":1234}}
+}
+
+func ExampleWithStatusEscalation() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithStatusEscalation(),
+		logs.WithStatusLevels(map[int]logs.Level{http.StatusNotFound: logs.INFO}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/invalid":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+
+	for _, path := range []string{"/missing", "/invalid", "/broken"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/missing","status":404,"response_bytes":0,"duration":1234}}
+	// {"@level":"WARN","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/invalid","status":400,"response_bytes":0,"duration":1234}}
+	// {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/broken","status":502,"response_bytes":0,"duration":1234}}
+}
+
+func ExampleStartTimer() {
+	timer := &manualTimer{}
+
+	ctx := logs.AddEntry(context.Background(), logs.Option(logs.WithTimer(timer)))
+
+	for i := 0; i < 2; i++ {
+		stop := logs.StartTimer(ctx, "db_query")
+		timer.Advance(20 * time.Millisecond)
+		stop()
+	}
+
+	timer.Advance(5 * time.Millisecond)
+	logs.Checkpoint(ctx, "cache_loaded")
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@timings":{"cache_loaded":45000000,"db_query":40000000}}
+}
+
+func TestStartTimer_concurrent(t *testing.T) {
+	ctx := logs.AddEntry(context.Background(), logs.Option(logs.WithTiming(time.Time{}, time.Millisecond)))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			logs.StartTimer(ctx, "db_query")()
+		}()
+		go func() {
+			defer wg.Done()
+			logs.Checkpoint(ctx, "cache_loaded")
+			logs.Count(ctx, "cache_hits", 1)
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := logs.GetValue(ctx, "@timings.db_query"); v != 10*time.Millisecond {
+		t.Errorf("expected a total of 10ms, got %v", v)
+	}
+}
+
+func ExampleIncr() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "cache.hits", 4)
+
+	logs.Incr(ctx, "cache.hits")
+	logs.Incr(ctx, "retries")
+	logs.Decr(ctx, "slots")
+	logs.AddFloat(ctx, "cost", 0.25)
+	logs.AddFloat(ctx, "cost", 0.5)
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","cache":{"hits":5},"cost":0.75,"retries":1,"slots":-1}
+}
+
+func ExampleCount() {
+	ctx := logs.AddEntry(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(attempts int) {
+			defer wg.Done()
+			logs.Count(ctx, "cache_hits", 1)
+			logs.Max(ctx, "retry_attempts", attempts%4)
+		}(i)
+	}
+	wg.Wait()
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@metrics":{"cache_hits":10,"retry_attempts":3}}
+}
+
+func ExamplePooling() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.Pooling(),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "path", r.URL.Path)
+	}))
+
+	for _, path := range []string{"/first", "/second"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/first","status":200,"response_bytes":0,"duration":1234},"path":"/first"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/second","status":200,"response_bytes":0,"duration":1234},"path":"/second"}
+}
+
+func ExamplePooling_printedByHandler() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.Pooling(),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "path", r.URL.Path)
+		logs.Print(r.Context(), logs.WithCurrentTime(time.Time{}))
+		logs.Add(r.Context(), "printed", true)
+	}))
+
+	for _, path := range []string{"/first", "/second"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","path":"/first"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/first","status":200,"response_bytes":0,"duration":1234},"path":"/first","printed":true}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","path":"/second"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"method":"GET","path":"/second","status":200,"response_bytes":0,"duration":1234},"path":"/second","printed":true}
+}
+
+func ExampleWithFatalBehavior() {
+	defer func() {
+		fmt.Println(recover())
+	}()
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "error", "out of disk space")
+	logs.Fatal(ctx)
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithFatalBehavior(logs.PanicOnFatal))
+	fmt.Println("unreachable")
+	// Output:
+	// {"@level":"FATAL","@time":"0001-01-01T00:00:00Z","error":"out of disk space"}
+	// logs: fatal log entry printed
+}
+
+func ExampleLevelHandler() {
+	level := logs.NewLevelVar(logs.INFO)
+	handler := logs.LevelHandler(level)
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "query", "SELECT 1")
+	logs.Debug(ctx)
+	fmt.Println(logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithLevelVar(level)))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/level", strings.NewReader(`{"level":"debug"}`))
+	handler.ServeHTTP(w, r)
+	fmt.Print(w.Body.String())
+
+	fmt.Println(logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithLevelVar(level)))
+	// Output:
+	// false
+	// {"level":"DEBUG"}
+	// {"@level":"DEBUG","@time":"0001-01-01T00:00:00Z","query":"SELECT 1"}
+	// true
+}
+
+func ExampleDetach() {
+	ctx, cancel := context.WithCancel(logs.AddEntry(context.Background()))
+	logs.Add(ctx, "request", "upload", "file.size", 1024)
+
+	detached := logs.Detach(ctx)
+	cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logs.Add(detached, "job", "thumbnail", "file.thumbnail", true)
+		fmt.Println(detached.Err())
+		logs.Print(detached, logs.WithCurrentTime(time.Time{}))
+	}()
+	wg.Wait()
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// <nil>
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","file":{"size":1024,"thumbnail":true},"job":"thumbnail","request":"upload"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","file":{"size":1024},"request":"upload"}
+}
+
+func TestDetach_concurrentCount(t *testing.T) {
+	ctx := logs.AddEntry(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			logs.Count(ctx, "cache_hits", 1)
+		}()
+		go func() {
+			defer wg.Done()
+			logs.Detach(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := logs.GetValue(ctx, "@metrics.cache_hits"); v != 10 {
+		t.Errorf("expected 10 cache hits, got %v", v)
+	}
+}
+
+func ExampleSnapshot() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "user.id", 42)
+
+	snapshot, _ := logs.Snapshot(ctx)
+	logs.Add(ctx, "user.role", "admin")
+
+	audit, _ := json.Marshal(snapshot)
+	fmt.Println(string(audit))
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"user":{"id":42}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","user":{"id":42,"role":"admin"}}
+}
+
+func ExampleMerge() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "request", "upload", "file.size", 1024)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	result := logs.FreeformEntry{}
+	go func() {
+		defer wg.Done()
+		work := logs.AddEntry(context.Background())
+		logs.Add(work, "thumbnail", true, "duration", 250)
+		result, _ = logs.Snapshot(work)
+	}()
+	wg.Wait()
+
+	logs.Merge(ctx, result, "file.resize")
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","file":{"resize":{"duration":250,"thumbnail":true},"size":1024},"request":"upload"}
+}
+
+func ExampleWithKeyNormalizer() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "userID", 42, "request-info.HTTPStatus", 200)
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithKeyNormalizer(logs.SnakeCase))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","request_info":{"http_status":200},"user_id":42}
+}
+
+func ExampleWithCollisionPolicy() {
+	ctx := logs.AddEntry(context.Background(), logs.WithCollisionPolicy(logs.CollisionRename))
+	logs.Add(ctx, "user", "alice")
+	logs.Add(ctx, "user.role", "admin")
+
+	strict := logs.AddEntry(context.Background(), logs.WithCollisionPolicy(logs.CollisionError))
+	logs.Add(strict, "user", "alice")
+	fmt.Println(errors.Is(logs.AddE(strict, "user.role", "admin"), logs.ErrKeyCollision))
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// true
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","user":{"role":"admin"},"user_1":"alice"}
+}
+
+func ExamplePrintE() {
+	err := logs.PrintE(context.Background())
+	fmt.Println(err, errors.Is(err, logs.ErrNoEntry))
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Debug(ctx)
+	err = logs.PrintE(ctx)
+	fmt.Println(err, errors.Is(err, logs.ErrNotPrinted))
+
+	logs.Finalize(ctx, logs.WithLevel(logs.DEBUG), logs.WithCurrentTime(time.Time{}))
+	fmt.Println(logs.AddE(ctx, "late", true))
+	// Output:
+	// no log entry in context true
+	// log entry not printed: level DEBUG is below INFO true
+	// {"@level":"DEBUG","@time":"0001-01-01T00:00:00Z"}
+	// log entry has been finalized
+}
+
+func ExampleSetStrict() {
+	logs.SetStrict(true)
+	defer logs.SetStrict(false)
+
+	defer func() {
+		fmt.Println("recovered:", recover())
+	}()
+
+	logs.Add(context.Background(), "key", "value")
+	// Output: recovered: no log entry in context
+}
+
+func ExampleWithTraceHeaders() {
+	middleware := logs.Middleware(logs.WithTiming(time.Time{}, time.Duration(1234)), logs.WithTraceHeaders())
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("tracestate", "vendor=value")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	r = httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set("b3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-0-05e3ac9a4f6e3b90")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@trace":{"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","sampled":true,"state":"vendor=value","format":"w3c"},"@http":{"method":"GET","path":"/path","status":200,"response_bytes":0,"duration":1234}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@trace":{"trace_id":"80f198ee56343ba864fe8b2a57d3eff7","span_id":"e457b5a2e4d86bd1","parent_id":"05e3ac9a4f6e3b90","sampled":false,"format":"b3"},"@http":{"method":"GET","path":"/path","status":200,"response_bytes":0,"duration":1234}}
+}
+
+type tenantKey struct{}
+
+func ExampleWithRequestContext() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithRequestContext(func(r *http.Request) context.Context {
+			return context.WithValue(r.Context(), tenantKey{}, r.Header.Get("X-Tenant"))
+		}),
+		logs.ContextFields(func(ctx context.Context) []logs.MetaField {
+			return []logs.MetaField{{Key: "tenant", Value: ctx.Value(tenantKey{})}}
+		}),
+	)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","tenant":"acme","@http":{"method":"GET","path":"/path","status":200,"response_bytes":0,"duration":1234}}
+}
+
+func ExampleWithExcludeKeys() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx,
+		"user", "bob",
+		"debug.query", "SELECT 1",
+		"debug.rows", 1,
+		"headers.Cookie", "session=secret",
+		"headers.Accept", "*/*",
+	)
+
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithExcludeKeys("headers.Cookie", "debug.*"),
+	)
+
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithIncludeKeys("user"),
+		logs.WithIncludeKeys("*.Accept"),
+	)
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","debug":{},"headers":{"Accept":"*/*"},"user":"bob"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","headers":{"Accept":"*/*"},"user":"bob"}
+}
+
+func ExampleNewRouter() {
+	router := logs.NewRouter(
+		logs.Sink{Writer: os.Stdout, Level: logs.INFO},
+		logs.Sink{Writer: os.Stdout, Encoder: logs.LogfmtEncoder{}, Level: logs.ERROR},
+	)
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "job", "cleanup")
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOutput(router))
+
+	ctx = logs.AddEntry(context.Background())
+	logs.Add(ctx, "job", "cleanup", "error", "disk full")
+	logs.Error(ctx)
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOutput(router))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","job":"cleanup"}
+	// {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","error":"disk full","job":"cleanup"}
+	// level=ERROR time=0001-01-01T00:00:00Z error="disk full" job=cleanup
+}
+
+func ExampleWithMaxEntrySize() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx,
+		"query", "SELECT id, name, email FROM users WHERE active",
+		"rows", 3,
+		"user.bio", "Writes Go at work and at home, and logs everything.",
+	)
+
+	for _, overflow := range []logs.Overflow{logs.OverflowDrop, logs.OverflowTruncate, logs.OverflowSplit} {
+		logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithMaxEntrySize(150, overflow))
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@truncated":["user"],"query":"SELECT id, name, email FROM users WHERE active","rows":3}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@truncated":["user.bio","query"],"query":"SELECT id...","rows":3,"user":{"bio":"Writes Go at w..."}}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@part":1,"@parts":2,"query":"SELECT id, name, email FROM users WHERE active","rows":3}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@part":2,"@parts":2,"user":{"bio":"Writes Go at work and at home, and logs everything."}}
+}
+
+func ExampleStdLogger() {
+	ctx := logs.AddEntry(context.Background())
+
+	logger := logs.StdLogger(ctx, logs.WARN, logs.WithStdLoggerKey("legacy"))
+	logger.Printf("retrying in %ds", 2)
+	logger.Println("giving up")
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"WARN","@time":"0001-01-01T00:00:00Z","legacy":["retrying in 2s","giving up"]}
+}
+
+func ExampleMsgf() {
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "user_id", 42)
+	logs.Msgf(ctx, "user %d not found", 42)
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+
+	ctx = logs.AddEntry(context.Background(), logs.WithMessageKey("msg"))
+	logs.Msg(ctx, "cache warmed")
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","message":"user 42 not found","user_id":42}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","msg":"cache warmed"}
+}
+
+func ExampleWithDurationFormat() {
+	middleware := logs.Middleware(
+		logs.WithTiming(time.Time{}, 12500*time.Microsecond),
+		logs.DurationFormat(logs.DurationMillis),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Add(r.Context(), "db.query_time", 3*time.Millisecond, "retry_after", "1s")
+	})).ServeHTTP(w, r)
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "elapsed", 1500*time.Millisecond)
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithDurationFormat(logs.DurationString))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@http":{"duration":12.5,"method":"GET","path":"/path","response_bytes":0,"status":200},"db":{"query_time":3},"retry_after":"1s"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","elapsed":"1.5s"}
+}
+
+func TestWithDurationFormat_encoders(t *testing.T) {
+	encoders := map[string]logs.Encoder{
+		"event.duration": logs.ECSEncoder{},
+		"duration":       logs.DatadogEncoder{},
+	}
+
+	for key, enc := range encoders {
+		for _, style := range []logs.DurationStyle{logs.DurationNanos, logs.DurationMillis, logs.DurationSeconds, logs.DurationString} {
+			var buf bytes.Buffer
+			middleware := logs.Middleware(
+				logs.WithTiming(time.Time{}, 1500*time.Millisecond),
+				logs.Output(&buf),
+				logs.Encoding(enc),
+				logs.DurationFormat(style),
+			)
+
+			r := httptest.NewRequest(http.MethodGet, "/path", nil)
+			middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(httptest.NewRecorder(), r)
+
+			var m map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+				t.Fatal(err)
+			}
+
+			var v any = m
+			for _, k := range strings.Split(key, ".") {
+				v = v.(map[string]any)[k]
+			}
+			if v != float64(1500*time.Millisecond) {
+				t.Errorf("%T with duration style %d: expected %s of 1.5s in nanoseconds, got %v", enc, style, key, v)
+			}
+		}
+	}
+}
+
+func ExampleNewRotatingFileWriter() {
+	dir, err := os.MkdirTemp("", "logs")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := logs.NewRotatingFileWriter(filepath.Join(dir, "app.log"), 100, 1, 7, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, job := range []string{"first", "second", "third"} {
+		ctx := logs.AddEntry(context.Background())
+		logs.Add(ctx, "job", job)
+		logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOutput(w))
+		w.Rotate()
+	}
+	w.Close()
+
+	files, _ := os.ReadDir(dir)
+	stamp := regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3}`)
+	for _, f := range files {
+		fmt.Println(stamp.ReplaceAllString(f.Name(), "<time>"))
+	}
+	// Output:
+	// app-<time>.log.gz
+	// app.log
+}
+
+func ExampleNewSyslogWriter() {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := logs.NewSyslogWriter("udp", conn.LocalAddr().String(), logs.FacilityLocal0, "api")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer w.Close()
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "path", "/users")
+	logs.Warn(ctx)
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOutput(w))
+
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	header := regexp.MustCompile(`^(<\d+>1) \S+ \S+ (\S+) \d+`)
+	fmt.Println(header.ReplaceAllString(string(buf[:n]), "$1 <time> <host> $2 <pid>"))
+	// Output:
+	// <132>1 <time> <host> api <pid> - - {"@level":"WARN","@time":"0001-01-01T00:00:00Z","path":"/users"}
+}
+
+func TestSyslogWriter_encoder(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := logs.NewSyslogWriter("udp", conn.LocalAddr().String(), logs.FacilityLocal0, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Error(ctx)
+	logs.Print(ctx, logs.WithOutput(w), logs.WithEncoder(logs.LogfmtEncoder{}))
+
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<131>1 ") {
+		t.Errorf("expected an error severity for a logfmt log entry, got %q", msg)
+	}
+}
+
+func ExampleAddLazy() {
+	count := func() any {
+		fmt.Println("counting rows")
+		return 42
+	}
+
+	ctx := logs.AddEntry(context.Background())
+	logs.AddLazy(ctx, "db.rows", count)
+	logs.Debug(ctx)
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithLevel(logs.DEBUG))
+	// Output:
+	// counting rows
+	// {"@level":"DEBUG","@time":"0001-01-01T00:00:00Z","db":{"rows":42}}
+}
+
+func ExampleJob() {
+	err := logs.Job(context.Background(), "send-digest", func(ctx context.Context) error {
+		logs.Add(ctx, "recipients", 42)
+		return errors.New("mail server unavailable")
+	}, logs.WithTiming(time.Time{}, time.Duration(1234)), logs.JobAttempt(2))
+
+	fmt.Println(err)
+	// Output:
+	// {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","@job":{"name":"send-digest","attempt":2,"duration":1234,"error":"mail server unavailable"},"recipients":42}
+	// mail server unavailable
+}
+
+func ExampleConsumeMiddleware() {
+	type message struct {
+		QueueURL     string
+		ID           string
+		ReceiveCount int
+		Body         string
+	}
+
+	handle := func(ctx context.Context, m message) error {
+		logs.Add(ctx, "body", m.Body)
+		return nil
+	}
+
+	consume := logs.ConsumeMiddleware(handle, func(m message) logs.MessageData {
+		return logs.SQSMessage(m.QueueURL, m.ID, m.ReceiveCount)
+	}, logs.WithTiming(time.Time{}, time.Duration(1234)))
+
+	consume(context.Background(), message{
+		QueueURL:     "https://sqs.us-east-1.amazonaws.com/123456789012/orders",
+		ID:           "a1b2c3",
+		ReceiveCount: 1,
+		Body:         "hello",
+	})
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@message":{"id":"a1b2c3","queue":"orders","attempt":1,"duration":1234,"outcome":"success"},"body":"hello"}
+}
+
+// fakeDriver is a database/sql driver whose statements affect one row.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func ExampleWrapDriver() {
+	sql.Register("fake+logs", logs.WrapDriver(fakeDriver{},
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithQueryArgs(func(arg driver.NamedValue) any {
+			if arg.Ordinal == 2 {
+				return logs.Redacted
+			}
+			return arg.Value
+		}),
+	))
+
+	db, err := sql.Open("fake+logs", "")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := logs.AddEntry(context.Background())
+	if _, err := db.ExecContext(ctx, "UPDATE users SET password = $2 WHERE id = $1", 42, "hunter2"); err != nil {
+		log.Fatal(err)
+	}
+
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@sql":[{"query":"UPDATE users SET password = $2 WHERE id = $1","args":[42,"[REDACTED]"],"rows_affected":1,"duration":1234}]}
+}
+
+func TestWithCaller(t *testing.T) {
+	caller := func(ctx context.Context) map[string]any {
+		var buf bytes.Buffer
+		logs.Print(ctx, logs.WithOutput(&buf))
+
+		var e map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	_, file, line, _ := runtime.Caller(0)
+	ctx := logs.AddEntry(context.Background(), logs.WithCaller(0))
+	want := fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+1)
+	if got := caller(ctx)["@caller"]; got != want {
+		t.Errorf("expected the call site that created the entry, %s, got %v", want, got)
+	}
+
+	logs.Error(ctx)
+	want = fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+7)
+	if got := caller(ctx)["@caller"]; got != want {
+		t.Errorf("expected the call site that leveled the entry, %s, got %v", want, got)
+	}
+
+	fail := func(ctx context.Context) { logs.Error(ctx) }
+	ctx = logs.AddEntry(context.Background(), logs.WithCallerFunction(1))
+	fail(ctx)
+	e := caller(ctx)
+	want = fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+15)
+	if got := e["@caller"]; got != want {
+		t.Errorf("expected the helper's caller, %s, got %v", want, got)
+	}
+	if got := e["@function"]; got != "github.com/rclark/logs_test.TestWithCaller" {
+		t.Errorf("expected the calling function, got %v", got)
+	}
+}
+
+func TestWithClientInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		trusted []string
+		want    string
+	}{
+		{"no proxies", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, nil, "203.0.113.7"},
+		{"untrusted proxy", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, []string{"10.0.0.1"}, "203.0.113.7"},
+		{"x-forwarded-for", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7"}, []string{"10.0.0.1"}, "203.0.113.7"},
+		{"forwarded", "10.0.0.1:1234", map[string]string{"Forwarded": `for="[2001:db8::1]:4711";proto=https, for=10.0.0.2`}, []string{"10.0.0.0/8"}, "2001:db8::1"},
+		{"all trusted", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3"}, []string{"10.0.0.0/8"}, "10.0.0.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			middleware := logs.Middleware(logs.Output(&buf), logs.WithClientInfo(), logs.WithTrustedProxies(tt.trusted...))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			middleware(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), r)
+
+			var e struct {
+				HTTP logs.HttpData `json:"@http"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.HTTP.ClientIP != tt.want {
+				t.Errorf("expected client IP %s, got %s", tt.want, e.HTTP.ClientIP)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := &http.Client{Transport: logs.Transport(nil)}
+	ctx := logs.AddEntry(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			if resp, err := client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+			logs.Incr(ctx, "requests")
+		}()
+	}
+	wg.Wait()
+
+	var buf bytes.Buffer
+	logs.Print(ctx, logs.WithOutput(&buf))
+
+	var e struct {
+		Requests []logs.HttpClientData `json:"@http_client"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Requests) != 8 {
+		t.Errorf("expected 8 requests to be recorded, got %d", len(e.Requests))
+	}
+}
+
+func TestWrapDriver(t *testing.T) {
+	connector, err := logs.WrapDriver(fakeDriver{}).(driver.DriverContext).OpenConnector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx := logs.AddEntry(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := db.ExecContext(ctx, "UPDATE users SET active = true"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var buf bytes.Buffer
+	logs.Print(ctx, logs.WithOutput(&buf))
+
+	var e struct {
+		Queries []logs.SqlData `json:"@sql"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Queries) != 8 {
+		t.Errorf("expected 8 queries to be recorded, got %d", len(e.Queries))
+	}
+}
+
+func TestRotatingFileWriter_failedRotation(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	w, err := logs.NewRotatingFileWriter(filepath.Join(dir, "app.log"), 0, 0, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Replacing the directory with a file makes rotation fail.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Rotate(); err == nil {
+		t.Fatal("expected rotation to fail")
+	}
+	if _, err := w.Write([]byte("lost\n")); err == nil || errors.Is(err, logs.ErrWriterClosed) {
+		t.Fatalf("expected the file to be unavailable, got %v", err)
+	}
+
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("kept\n")); err != nil {
+		t.Fatalf("expected the file to be opened again, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "kept\n" {
+		t.Errorf("unexpected file contents %q", data)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Unix(0, 0)
+	limiter := logs.NewRateLimiter(1, time.Second, logs.RateLimitByField("route"), logs.RateLimitSummary(10*time.Second))
+	limit := logs.WithRateLimit(limiter)
+
+	print := func(route string) bool {
+		ctx := logs.AddEntry(context.Background())
+		logs.Add(ctx, "route", route)
+		return logs.PrintE(ctx, logs.WithOutput(&buf), logs.WithCurrentTime(now), limit) == nil
+	}
+
+	if !print("/a") || !print("/b") {
+		t.Fatal("expected the first log entry for each route to be printed")
+	}
+	if print("/a") || print("/a") {
+		t.Fatal("expected log entries over the limit to be dropped")
+	}
+
+	now = now.Add(time.Second)
+	if !print("/a") {
+		t.Fatal("expected the limit to refill")
+	}
+
+	buf.Reset()
+	now = now.Add(10 * time.Second)
+	print("/b")
+
+	var summary struct {
+		RateLimit struct {
+			Dropped int            `json:"dropped"`
+			Keys    map[string]int `json:"keys"`
+		} `json:"@rate_limit"`
+	}
+	if err := json.NewDecoder(&buf).Decode(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.RateLimit.Dropped != 2 || summary.RateLimit.Keys["/a"] != 2 || len(summary.RateLimit.Keys) != 1 {
+		t.Errorf("unexpected summary: %+v", summary.RateLimit)
+	}
+
+	buf.Reset()
+	if print("/b") || print("/b") {
+		t.Fatal("expected log entries over the limit to be dropped")
+	}
+	if buf.Len() > 0 {
+		t.Fatalf("expected no summary before the interval, got %s", buf.String())
+	}
+
+	if err := limiter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	summary.RateLimit.Keys = nil
+	if err := json.NewDecoder(&buf).Decode(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.RateLimit.Dropped != 2 || summary.RateLimit.Keys["/b"] != 2 || len(summary.RateLimit.Keys) != 1 {
+		t.Errorf("unexpected final summary: %+v", summary.RateLimit)
+	}
+
+	buf.Reset()
+	if err := limiter.Flush(); err != nil || buf.Len() > 0 {
+		t.Errorf("expected nothing to flush, got %q, %v", buf.String(), err)
+	}
 }
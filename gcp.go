@@ -0,0 +1,146 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// GCPEncoder formats log entries as JSON objects in the shape that Google Cloud
+// Logging expects. The level is written as "severity" using Cloud Logging's
+// severity names, the time is written as "time", and the HTTP data that the
+// middleware writes under the "@http" key is mapped into an "httpRequest"
+// object. Any fields of the HTTP data that have no equivalent remain under
+// "@http". The trace fields added by the otellog package's WithTrace option are
+// written as the "logging.googleapis.com/trace",
+// "logging.googleapis.com/spanId", and "logging.googleapis.com/trace_sampled"
+// fields.
+type GCPEncoder struct {
+	// ProjectID is used to write the trace field as a full resource name. If it
+	// is empty, the trace field holds only the trace ID.
+	ProjectID string
+}
+
+// GCPFormat configures printing to format log entries for Google Cloud Logging
+// using a [GCPEncoder]. The project ID is read from the GOOGLE_CLOUD_PROJECT
+// environment variable. To use this format with the middleware, provide the
+// [Encoding] option instead.
+func GCPFormat() PrintOption {
+	project, _ := lookupEnv("GOOGLE_CLOUD_PROJECT")
+
+	return func(o *option) {
+		o.encoder = GCPEncoder{ProjectID: project}
+	}
+}
+
+// Encode formats the log entry as a JSON object for Google Cloud Logging.
+func (g GCPEncoder) Encode(meta Metadata, entry any) ([]byte, error) {
+	data, err := marshalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := toMap(data)
+	if !ok {
+		m = map[string]any{"message": json.RawMessage(data)}
+	}
+
+	for _, f := range meta.Fields {
+		switch f.Key {
+		case "trace_id":
+			m["logging.googleapis.com/trace"] = g.trace(f.Value)
+		case "span_id":
+			m["logging.googleapis.com/spanId"] = f.Value
+		case "trace_flags":
+			m["logging.googleapis.com/trace_sampled"] = f.Value == "01"
+		default:
+			m[f.Key] = f.Value
+		}
+	}
+
+	if http, ok := m["@http"].(map[string]any); ok {
+		m["httpRequest"] = gcpHttpRequest(http, meta.DurationFormat)
+		if len(http) == 0 {
+			delete(m, "@http")
+		}
+	}
+
+	meta.TimeFormat = time.RFC3339Nano
+	m["severity"] = gcpSeverity(meta.Level)
+	m["time"] = meta.FormatTime()
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+// trace formats the trace field.
+func (g GCPEncoder) trace(id any) any {
+	if g.ProjectID == "" {
+		return id
+	}
+
+	return fmt.Sprintf("projects/%s/traces/%v", g.ProjectID, id)
+}
+
+// gcpHttpRequest moves the fields of the middleware's HTTP data that have
+// equivalents in Cloud Logging's HttpRequest structure into a new map. The
+// duration is read in the style that it was written.
+func gcpHttpRequest(http map[string]any, durations DurationStyle) map[string]any {
+	req := make(map[string]any)
+
+	move := func(from, to string, convert func(any) any) {
+		if v, ok := http[from]; ok {
+			req[to] = convert(v)
+			delete(http, from)
+		}
+	}
+
+	same := func(v any) any { return v }
+
+	move("method", "requestMethod", same)
+	move("path", "requestUrl", same)
+	move("status", "status", same)
+	move("client_ip", "remoteIp", same)
+	move("user_agent", "userAgent", same)
+	move("referer", "referer", same)
+	move("response_bytes", "responseSize", func(v any) any {
+		return consoleValue(v)
+	})
+
+	if d, ok := durations.parse(http["duration"]); ok {
+		req["latency"] = strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+		delete(http, "duration")
+	}
+
+	if headers, ok := http["headers"].(map[string]any); ok {
+		if ua, ok := headers["User-Agent"]; ok {
+			req["userAgent"] = ua
+		}
+		if ref, ok := headers["Referer"]; ok {
+			req["referer"] = ref
+		}
+	}
+
+	return req
+}
+
+// gcpSeverity maps a level to the name of a Cloud Logging severity.
+func gcpSeverity(level Level) string {
+	switch {
+	case level >= FATAL:
+		return "CRITICAL"
+	case level >= ERROR:
+		return "ERROR"
+	case level >= WARN:
+		return "WARNING"
+	case level >= INFO:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
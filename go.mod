@@ -1,3 +1,20 @@
 module github.com/rclark/logs
 
 go 1.22.4
+
+require (
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+	go.opentelemetry.io/proto/otlp v1.3.1
+	google.golang.org/grpc v1.65.0
+	google.golang.org/protobuf v1.34.1
+)
+
+require (
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.20.0 // indirect
+	golang.org/x/net v0.25.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20240528184218-531527333157 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
+)
@@ -0,0 +1,98 @@
+// Package grpclog provides gRPC server interceptors that add freeform,
+// context-based logging from the logs package to each RPC, like the logs
+// package's Middleware does for HTTP handlers.
+package grpclog
+
+import (
+	"context"
+	"time"
+
+	"github.com/rclark/logs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Data is the data structure that the interceptors write into each log entry
+// under the "@grpc" key.
+type Data struct {
+	Method   string        `json:"method"`
+	Peer     string        `json:"peer,omitempty"`
+	Code     string        `json:"code"`
+	Duration time.Duration `json:"duration"`
+}
+
+// UnaryServerInterceptor adds freeform, context-based logging to a gRPC
+// server's unary RPCs. A logs.FreeformEntry is added to each RPC's context, and
+// the RPC's method, peer, status code, and duration are written into it under
+// the "@grpc" key before it is printed. The options configure the log entry and
+// how it is printed, as they do for the logs package's Middleware.
+func UnaryServerInterceptor(opts ...logs.MiddlewareOption) grpc.UnaryServerInterceptor {
+	entryOpts, printOpts := options(opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = logs.AddEntry(ctx, entryOpts...)
+
+		resp, err := handler(ctx, req)
+
+		logs.Add(ctx, "@grpc", newData(ctx, info.FullMethod, err))
+		logs.Print(ctx, printOpts...)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor adds freeform, context-based logging to a gRPC
+// server's streaming RPCs, like [UnaryServerInterceptor] does for unary RPCs.
+// The log entry is printed once the stream's handler returns.
+func StreamServerInterceptor(opts ...logs.MiddlewareOption) grpc.StreamServerInterceptor {
+	entryOpts, printOpts := options(opts)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := logs.AddEntry(ss.Context(), entryOpts...)
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+
+		logs.Add(ctx, "@grpc", newData(ctx, info.FullMethod, err))
+		logs.Print(ctx, printOpts...)
+		return err
+	}
+}
+
+// options converts middleware options to the options used to create and print
+// a log entry.
+func options(opts []logs.MiddlewareOption) ([]logs.Option, []logs.PrintOption) {
+	entryOpts := make([]logs.Option, len(opts))
+	printOpts := make([]logs.PrintOption, len(opts))
+	for i, opt := range opts {
+		entryOpts[i] = logs.Option(opt)
+		printOpts[i] = logs.PrintOption(opt)
+	}
+
+	return entryOpts, printOpts
+}
+
+// serverStream replaces a gRPC server stream's context with one that contains
+// a log entry.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func newData(ctx context.Context, method string, err error) Data {
+	d, _ := logs.Elapsed(ctx)
+	data := Data{
+		Method:   method,
+		Code:     status.Code(err).String(),
+		Duration: d,
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		data.Peer = p.Addr.String()
+	}
+
+	return data
+}
@@ -0,0 +1,51 @@
+package grpclog_test
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/rclark/logs"
+	"github.com/rclark/logs/grpclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func ExampleUnaryServerInterceptor() {
+	interceptor := grpclog.UnaryServerInterceptor(logs.WithTiming(time.Time{}, time.Duration(1234)))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000},
+	})
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.Users/Get"}
+
+	_, _ = interceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		logs.Add(ctx, "user", 1234)
+		return nil, status.Error(codes.NotFound, "no such user")
+	})
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@grpc":{"method":"/users.Users/Get","peer":"10.0.0.1:5000","code":"NotFound","duration":1234},"user":1234}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func ExampleStreamServerInterceptor() {
+	interceptor := grpclog.StreamServerInterceptor(logs.WithTiming(time.Time{}, time.Duration(1234)))
+
+	stream := fakeStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/users.Users/List", IsServerStream: true}
+
+	_ = interceptor(nil, stream, info, func(srv any, ss grpc.ServerStream) error {
+		logs.Add(ss.Context(), "sent", 3)
+		return nil
+	})
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@grpc":{"method":"/users.Users/List","code":"OK","duration":1234},"sent":3}
+}
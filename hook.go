@@ -0,0 +1,62 @@
+package logs
+
+import "errors"
+
+// ErrSkipEntry may be returned by a hook to prevent a log entry from being
+// printed without reporting an error.
+var ErrSkipEntry = errors.New("skip log entry")
+
+// WithHook registers a hook on the log entry. The hook is called each time the
+// log entry is printed, just before it is encoded, with the level that it is
+// printed at. Hooks may enrich, validate, or mutate the log entry in one place,
+// rather than in every call to [Adjust]. Changes made by a hook remain in the
+// log entry after it is printed.
+//
+// If a hook returns an error, the log entry is not printed. The error is
+// written to os.Stderr unless it is [ErrSkipEntry]. Hooks whose type does not
+// match the log entry's type are ignored, and a diagnostic message is written
+// to os.Stderr; use FreeformEntry as the type for freeform log entries.
+func WithHook[T any](fn func(level Level, entry *T) error) Option {
+	return func(o *option) {
+		o.hooks = append(o.hooks, fn)
+	}
+}
+
+// Hook registers a hook on the log entries produced by the middleware, as
+// described by [WithHook].
+func Hook[T any](fn func(level Level, entry *T) error) MiddlewareOption {
+	return MiddlewareOption(WithHook(fn))
+}
+
+// WithHooks creates a copy of the logger that registers hooks on every log
+// entry it creates, as described by [WithHook].
+func (logger Logger[T]) WithHooks(fns ...func(level Level, entry *T) error) Logger[T] {
+	logger.hooks = append(append([]func(Level, *T) error{}, logger.hooks...), fns...)
+	return logger
+}
+
+// runHooks calls the log entry's hooks in the order they were registered,
+// stopping at the first error.
+func (e *entry[T]) runHooks(level Level) error {
+	for _, hook := range e.hooks {
+		if err := hook(level, e.data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entryHooks selects the hooks that match the log entry's type.
+func entryHooks[T any](o option) []func(Level, *T) error {
+	var hooks []func(Level, *T) error
+	for _, h := range o.hooks {
+		if hook, ok := h.(func(Level, *T) error); ok {
+			hooks = append(hooks, hook)
+		} else {
+			mismatched[T]("hook", h)
+		}
+	}
+
+	return hooks
+}
@@ -0,0 +1,720 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithBody configures the middleware to write request bodies into each log
+// entry. This option will have no effect unless [Middleware] is operating on a
+// [FreeformEntry], or the custom log entry type implements [HttpDataReceiver].
+func WithBody() MiddlewareOption {
+	return func(o *option) {
+		o.body = true
+	}
+}
+
+// WithEagerBody configures the middleware to write request bodies into each log
+// entry, like [WithBody]. Rather than recording the body as the handler reads
+// it, the middleware reads the whole body before calling the handler, and gives
+// the handler a copy. This guarantees that the body is logged even if the
+// handler does not read it, at the cost of holding the whole body in memory.
+func WithEagerBody() MiddlewareOption {
+	return func(o *option) {
+		o.body = true
+		o.eagerBody = true
+	}
+}
+
+// WithBodyLimit configures the middleware to write at most n bytes of each
+// request body into the log entry, when [WithBody] or [WithEagerBody] is also
+// used. The handler still receives the whole body. The true length of the body
+// is written into the log entry as "body_bytes", and "body_truncated" is set if
+// the body was longer than n. With [WithEagerBody], only the first n bytes are
+// read before calling the handler, so the true length includes only what the
+// handler goes on to read.
+func WithBodyLimit(n int) MiddlewareOption {
+	return func(o *option) {
+		o.bodyLimit = n
+	}
+}
+
+// WithBodySkipTypes configures the middleware not to write the bodies of
+// requests with the specified content types into log entries, even if
+// [WithBody] or [WithEagerBody] is used. A type like "image/*" matches any
+// subtype.
+func WithBodySkipTypes(types ...string) MiddlewareOption {
+	return func(o *option) {
+		o.bodySkipTypes = append(o.bodySkipTypes, types...)
+	}
+}
+
+// capturesBody reports whether the middleware should write the request's body
+// into the log entry.
+func (o option) capturesBody(r *http.Request) bool {
+	if !o.body {
+		return false
+	}
+
+	if len(o.bodySkipTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return true
+	}
+
+	for _, t := range o.bodySkipTypes {
+		if strings.EqualFold(t, mediaType) {
+			return false
+		}
+		if prefix, ok := strings.CutSuffix(t, "*"); ok && strings.HasPrefix(mediaType, strings.ToLower(prefix)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithAllHeaders configures the middleware to write all request headers into
+// each log entry. This option will have no effect unless [Middleware] is
+// operating on a [FreeformEntry], or the custom log entry type implements
+// [HttpDataReceiver].
+func WithAllHeaders() MiddlewareOption {
+	return func(o *option) {
+		o.allHeaders = true
+	}
+}
+
+// WithHeaders configures the middleware to write specific request headers into
+// each log entry. This option will have no effect unless [Middleware] is
+// operating on a [FreeformEntry], or the custom log entry type implements
+// [HttpDataReceiver].
+func WithHeaders(headers ...string) MiddlewareOption {
+	return func(o *option) {
+		o.someHeaders = headers
+	}
+}
+
+// BodyEncoding determines how request bodies are written into log entries.
+type BodyEncoding int
+
+const (
+	// BodyRaw writes request bodies into log entries as-is. This is the
+	// default.
+	BodyRaw BodyEncoding = iota
+	// BodyBase64 writes request bodies into log entries using standard base64
+	// encoding. This is useful for binary bodies.
+	BodyBase64
+	// BodyHex writes request bodies into log entries using hexadecimal
+	// encoding. This is useful for binary bodies.
+	BodyHex
+)
+
+func (e BodyEncoding) encode(body []byte) string {
+	switch e {
+	case BodyBase64:
+		return base64.StdEncoding.EncodeToString(body)
+	case BodyHex:
+		return hex.EncodeToString(body)
+	default:
+		return string(body)
+	}
+}
+
+// WithBodyEncoding configures how the middleware encodes request bodies when
+// writing them into each log entry. This option will have no effect unless
+// [WithBody] is also used.
+func WithBodyEncoding(encoding BodyEncoding) MiddlewareOption {
+	return func(o *option) {
+		o.bodyEncoding = encoding
+	}
+}
+
+// WithAttemptHeader configures the middleware to read a retry attempt number
+// from the named request header, such as "X-Retry-Count", and write it into
+// each log entry. The attempt will be 0 if the header is absent or is not an
+// integer.
+func WithAttemptHeader(name string) MiddlewareOption {
+	return func(o *option) {
+		o.attemptHeader = name
+	}
+}
+
+// WithTTFB configures the middleware to write the time to first byte into each
+// log entry. This is the time from the start of the request until the handler
+// first writes to the response. If the handler never writes to the response,
+// no time to first byte is recorded.
+func WithTTFB() MiddlewareOption {
+	return func(o *option) {
+		o.ttfb = true
+	}
+}
+
+// WithHandlerName configures the middleware to write the name of the handler
+// that serves the request into each log entry. Use this when wrapping the
+// handler for a specific route.
+func WithHandlerName(name string) MiddlewareOption {
+	return func(o *option) {
+		o.handler = name
+	}
+}
+
+// WithQuery configures the middleware to write the request's query parameters
+// into each log entry. This option will have no effect unless [Middleware] is
+// operating on a [FreeformEntry], or the custom log entry type implements
+// [HttpDataReceiver].
+func WithQuery() MiddlewareOption {
+	return func(o *option) {
+		o.query = true
+	}
+}
+
+// WithRoutePattern configures the middleware to write the pattern of the route
+// that served the request, such as "/users/{id}", into each log entry, so that
+// logs can be grouped by route rather than by path. The function is called with
+// the request after the handler returns, so it can read a pattern that a router
+// stored in the request while routing. The route is omitted if the function
+// returns an empty string.
+func WithRoutePattern(fn func(*http.Request) string) MiddlewareOption {
+	return func(o *option) {
+		o.route = fn
+	}
+}
+
+// WithResponseHeaders configures the middleware to write specific response
+// headers into each log entry. This option will have no effect unless
+// [Middleware] is operating on a [FreeformEntry], or the custom log entry type
+// implements [HttpDataReceiver].
+func WithResponseHeaders(headers ...string) MiddlewareOption {
+	return func(o *option) {
+		o.responseHeaders = headers
+	}
+}
+
+// WithResponseBody configures the middleware to write up to limit bytes of each
+// response body into the log entry, encoded as configured by
+// [WithBodyEncoding]. This is useful for debugging the error payloads of an
+// API, but can write sensitive data into logs. This option will have no effect
+// unless [Middleware] is operating on a [FreeformEntry], or the custom log
+// entry type implements [HttpDataReceiver].
+func WithResponseBody(limit int) MiddlewareOption {
+	return func(o *option) {
+		o.responseBody = limit
+	}
+}
+
+// HttpDataReceiver may be implemented by a custom log entry type that accepts
+// HTTP data from the middleware. If a pointer to the log entry type implements
+// HttpDataReceiver, the middleware calls SetHttpData once the request has been
+// handled. Options like [WithBody] and [WithHeaders] are honored.
+type HttpDataReceiver interface {
+	SetHttpData(HttpData)
+}
+
+// WithLayerTiming configures the middleware to record the duration of the
+// downstream handler in each log entry, under the "@timing.<name>" key of a
+// [FreeformEntry], or by calling SetLayerTiming if a pointer to a custom log
+// entry type implements [LayerTimingReceiver]. When middlewares that use this
+// option are nested, an inner middleware joins the log entry created by the
+// outermost one rather than creating and printing its own, so that all of
+// their durations are recorded in the same log entry.
+func WithLayerTiming(name string) MiddlewareOption {
+	return func(o *option) {
+		o.layer = name
+	}
+}
+
+// LayerTimingReceiver may be implemented by a custom log entry type that
+// accepts the durations recorded by middlewares using [WithLayerTiming].
+type LayerTimingReceiver interface {
+	SetLayerTiming(name string, d time.Duration)
+}
+
+// recordLayer records the duration of a middleware layer in the log entry in
+// the context.
+func recordLayer[T any](ctx context.Context, name string, d time.Duration) {
+	if _, ok := any((*T)(nil)).(*FreeformEntry); ok {
+		Add(ctx, "@timing."+name, d)
+		return
+	}
+
+	Logger[T]{}.Adjust(ctx, func(e *T) {
+		if receiver, ok := any(e).(LayerTimingReceiver); ok {
+			receiver.SetLayerTiming(name, d)
+		}
+	})
+}
+
+// HttpData is the data structure for HTTP data that the middleware will apply
+// to log entries under the `@http` key of a [FreeformEntry]. It describes both
+// the request and the response, including the response's status code and the
+// number of bytes written to its body.
+type HttpData struct {
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Route      string            `json:"route,omitempty"`
+	Handler    string            `json:"handler,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	ClientIP   string            `json:"client_ip,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	Referer    string            `json:"referer,omitempty"`
+	Conn       *ConnData         `json:"conn,omitempty"`
+	Query      url.Values        `json:"query,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	BodyBytes  int               `json:"body_bytes,omitempty"`
+	Truncated  bool              `json:"body_truncated,omitempty"`
+	Status     int               `json:"status"`
+	Bytes      int               `json:"response_bytes"`
+	Response   map[string]string `json:"response_headers,omitempty"`
+	RespBody   string            `json:"response_body,omitempty"`
+	Attempt    *int              `json:"attempt,omitempty"`
+	TTFB       *time.Duration    `json:"ttfb,omitempty"`
+	Duration   time.Duration     `json:"duration"`
+}
+
+// bodyWatcher records a request body as it is read, keeping no more than the
+// limit, if there is one, and counting every byte.
+type bodyWatcher struct {
+	io.ReadCloser
+	buf   *bytes.Buffer
+	limit int
+	total int
+}
+
+func (bw *bodyWatcher) Read(p []byte) (int, error) {
+	n, err := bw.ReadCloser.Read(p)
+	bw.total += n
+	if room := bw.limit - bw.buf.Len(); bw.limit <= 0 {
+		bw.buf.Write(p[:n])
+	} else if room > 0 {
+		bw.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
+
+// full reports whether the watcher has recorded as much as its limit allows.
+func (bw *bodyWatcher) full() bool {
+	return bw.limit > 0 && bw.buf.Len() >= bw.limit
+}
+
+// responseWriter watches the response as the handler writes it.
+type responseWriter struct {
+	http.ResponseWriter
+	timer  Timer
+	start  time.Time
+	ttfb   *time.Duration
+	status int
+	bytes  int
+	body   *bytes.Buffer
+	limit  int
+}
+
+func (rw *responseWriter) mark() {
+	if rw.ttfb == nil {
+		ttfb := rw.timer.Since(rw.start)
+		rw.ttfb = &ttfb
+	}
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.mark()
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.mark()
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytes += n
+	if rw.body != nil {
+		if room := rw.limit - rw.body.Len(); room > 0 {
+			rw.body.Write(p[:min(n, room)])
+		}
+	}
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the underlying
+// [http.ResponseWriter] supports it.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.mark()
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap allows an [http.ResponseController] to access the underlying
+// [http.ResponseWriter].
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// readEagerly reads the whole body through the watcher, or as much as the
+// watcher's limit allows, and returns a replacement body that provides the same
+// data. If reading the body fails, the replacement body returns the data that
+// was read followed by the error.
+func readEagerly(bw *bodyWatcher) io.ReadCloser {
+	if bw.ReadCloser == nil || bw.ReadCloser == http.NoBody {
+		return bw.ReadCloser
+	}
+
+	n := int64(math.MaxInt64)
+	if bw.limit > 0 {
+		n = int64(bw.limit)
+	}
+
+	_, err := io.Copy(io.Discard, io.LimitReader(bw, n))
+	read := bytes.NewReader(bw.buf.Bytes())
+	if err == nil && bw.full() {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(read, bw), bw}
+	}
+
+	bw.Close()
+
+	var r io.Reader = read
+	if err != nil {
+		r = io.MultiReader(r, errReader{err})
+	}
+
+	return io.NopCloser(r)
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// WithRecovery configures the middleware to recover from panics in the
+// downstream handler. When a panic is recovered, the log entry's level is set
+// to ERROR, the panic's value and stack trace are written into the log entry
+// under the "@panic" key, and the log entry is printed as usual. If the handler
+// has not yet written a response, a 500 Internal Server Error response is
+// written. Panics with [http.ErrAbortHandler] are not recovered.
+func WithRecovery() MiddlewareOption {
+	return func(o *option) {
+		o.recovery = true
+	}
+}
+
+// WithRequestID configures the middleware to assign an ID to each request. The
+// ID is read from the incoming "X-Request-Id" header, or a random UUID is
+// generated if the header is absent. The ID is written into each log entry as
+// the "request_id" of its HTTP data, set on the response's "X-Request-Id"
+// header, and made available to the downstream handler using [RequestID].
+func WithRequestID() MiddlewareOption {
+	return WithRequestIDHeader("X-Request-Id")
+}
+
+// WithRequestIDHeader works like [WithRequestID], but reads and writes the
+// request ID using the named header instead of "X-Request-Id".
+func WithRequestIDHeader(name string) MiddlewareOption {
+	return func(o *option) {
+		o.requestID = name
+	}
+}
+
+type requestIDKey struct{}
+
+var ridKey = requestIDKey{}
+
+// RequestID retrieves the ID that the middleware assigned to the request when
+// the [WithRequestID] option is used. The function will return an empty string
+// if no request ID is found in the context.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ridKey).(string)
+	return id
+}
+
+// withRequestID assigns an ID to the request, if the middleware is configured
+// to do so, and sets it on the response.
+func withRequestID(w http.ResponseWriter, r *http.Request, opt option) *http.Request {
+	if opt.requestID == "" {
+		return r
+	}
+
+	id := r.Header.Get(opt.requestID)
+	if id == "" {
+		id = newUUID()
+	}
+
+	w.Header().Set(opt.requestID, id)
+	return r.WithContext(context.WithValue(r.Context(), ridKey, id))
+}
+
+// newUUID generates a random, version 4 UUID.
+func newUUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// WithSkipPaths configures the middleware to skip logging for requests to the
+// specified paths, such as health checks. No log entry is added to the context
+// of a skipped request.
+func WithSkipPaths(paths ...string) MiddlewareOption {
+	return func(o *option) {
+		o.skipPaths = append(o.skipPaths, paths...)
+	}
+}
+
+// WithSkipFunc configures the middleware to skip logging for requests for which
+// fn returns true. No log entry is added to the context of a skipped request.
+func WithSkipFunc(fn func(*http.Request) bool) MiddlewareOption {
+	return func(o *option) {
+		o.skipFunc = fn
+	}
+}
+
+// skips reports whether the middleware should skip logging for the request.
+func (o option) skips(r *http.Request) bool {
+	for _, path := range o.skipPaths {
+		if r.URL.Path == path {
+			return true
+		}
+	}
+
+	return o.skipFunc != nil && o.skipFunc(r)
+}
+
+// WithStatusLevels configures the middleware to raise the level of each log
+// entry based on the status code of the response. The levels map exact status
+// codes to levels. The log entry's level is only raised, so a higher level set
+// by the handler is kept.
+func WithStatusLevels(levels map[int]Level) MiddlewareOption {
+	return func(o *option) {
+		if o.statusLevels == nil {
+			o.statusLevels = make(map[int]Level)
+		}
+		for status, level := range levels {
+			o.statusLevels[status] = level
+		}
+	}
+}
+
+// WithStatusEscalation configures the middleware to raise the level of each
+// log entry to WARN for 4xx responses and to ERROR for 5xx responses. Levels
+// for specific status codes can be set using [WithStatusLevels], which takes
+// precedence.
+func WithStatusEscalation() MiddlewareOption {
+	return func(o *option) {
+		o.statusClasses = true
+	}
+}
+
+// statusLevel chooses the level for a response's status code. The function
+// will return false if no level applies.
+func (o option) statusLevel(status int) (Level, bool) {
+	if level, ok := o.statusLevels[status]; ok {
+		return level, true
+	}
+
+	if o.statusClasses {
+		switch {
+		case status >= 500:
+			return ERROR, true
+		case status >= 400:
+			return WARN, true
+		}
+	}
+
+	return 0, false
+}
+
+// escalate raises the level of the log entry in the context based on the
+// status code of the response.
+func escalate[T any](ctx context.Context, status int, opt option) {
+	level, ok := opt.statusLevel(status)
+	if !ok {
+		return
+	}
+
+	if e := getEntry[T](ctx); e != nil && e.currentLevel() < level {
+		e.setLevel(level)
+	}
+}
+
+// PanicData is the data structure for a recovered panic that the middleware
+// will apply to log entries under the `@panic` key of a [FreeformEntry].
+type PanicData struct {
+	Value string `json:"value"`
+	Stack string `json:"stack"`
+}
+
+// serve calls the handler, recovering from any panic if configured to. The
+// function returns data describing a recovered panic, or nil.
+func serve(next http.Handler, w *responseWriter, r *http.Request, opt option) (recovered *PanicData) {
+	if opt.recovery {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			stack := make([]byte, 64<<10)
+			stack = stack[:runtime.Stack(stack, false)]
+
+			recovered = &PanicData{Value: fmt.Sprint(v), Stack: string(stack)}
+			if w.status == 0 {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+	}
+
+	next.ServeHTTP(w, r)
+	return nil
+}
+
+// capture collects HTTP data over the lifetime of a request.
+type capture struct {
+	opt   option
+	r     *http.Request
+	w     *responseWriter
+	start time.Time
+	body  *bodyWatcher
+	data  HttpData
+}
+
+// startCapture begins collecting HTTP data for the request. The handler should
+// be given the capture's response writer. If the body is to be logged, the
+// request's body is wrapped so that it is recorded as the handler reads it.
+func startCapture(w http.ResponseWriter, r *http.Request, opt option) *capture {
+	start := opt.timer.Now()
+
+	c := &capture{
+		opt:   opt,
+		r:     r,
+		w:     &responseWriter{ResponseWriter: w, timer: opt.timer, start: start, limit: opt.responseBody},
+		start: start,
+		data:  HttpData{Method: r.Method, Path: r.URL.Path, Handler: opt.handler, RequestID: RequestID(r.Context())},
+	}
+
+	if opt.responseBody > 0 {
+		c.w.body = new(bytes.Buffer)
+	}
+
+	if opt.capturesBody(r) {
+		c.body = &bodyWatcher{ReadCloser: r.Body, buf: new(bytes.Buffer), limit: opt.bodyLimit}
+		if opt.eagerBody {
+			r.Body = readEagerly(c.body)
+		} else {
+			r.Body = c.body
+		}
+	}
+
+	return c
+}
+
+// finish completes the HTTP data once the handler has returned.
+func (c *capture) finish() HttpData {
+	c.data.Duration = c.opt.timer.Since(c.start)
+	if c.body != nil {
+		c.data.Body = c.opt.bodyEncoding.encode(c.body.buf.Bytes())
+		if c.body.limit > 0 {
+			c.data.BodyBytes = c.body.total
+			c.data.Truncated = c.body.total > c.body.buf.Len()
+		}
+	}
+
+	if c.opt.route != nil {
+		c.data.Route = c.opt.route(c.r)
+	}
+
+	if c.opt.query {
+		c.data.Query = c.r.URL.Query()
+	}
+
+	if len(c.opt.someHeaders) > 0 {
+		c.data.Headers = make(map[string]string)
+		for _, h := range c.opt.someHeaders {
+			c.data.Headers[h] = c.r.Header.Get(h)
+		}
+	} else if c.opt.allHeaders {
+		c.data.Headers = make(map[string]string)
+		for k := range c.r.Header {
+			c.data.Headers[k] = c.r.Header.Get(k)
+		}
+	}
+
+	c.data.Status = c.w.status
+	if c.data.Status == 0 {
+		c.data.Status = http.StatusOK
+	}
+	c.data.Bytes = c.w.bytes
+
+	if len(c.opt.responseHeaders) > 0 {
+		c.data.Response = make(map[string]string)
+		for _, h := range c.opt.responseHeaders {
+			c.data.Response[h] = c.w.Header().Get(h)
+		}
+	}
+
+	if c.w.body != nil {
+		c.data.RespBody = c.opt.bodyEncoding.encode(c.w.body.Bytes())
+	}
+
+	if c.opt.ttfb {
+		c.data.TTFB = c.w.ttfb
+	}
+
+	if c.opt.clientInfo {
+		c.data.RemoteAddr = c.r.RemoteAddr
+		c.data.ClientIP = clientIP(c.r, c.opt.trustedProxies)
+		c.data.UserAgent = c.r.UserAgent()
+		c.data.Referer = c.r.Referer()
+	}
+
+	if c.opt.connInfo {
+		c.data.Conn = connData(c.r)
+	}
+
+	if c.opt.attemptHeader != "" {
+		attempt, err := strconv.Atoi(c.r.Header.Get(c.opt.attemptHeader))
+		if err != nil {
+			attempt = 0
+		}
+		c.data.Attempt = &attempt
+	}
+
+	return c.data
+}
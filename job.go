@@ -0,0 +1,91 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// JobData is the data structure that [Job] writes into log entries under the
+// "@job" key. It describes a single run of a background job.
+type JobData struct {
+	Name     string        `json:"name"`
+	Attempt  int           `json:"attempt"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Panic    *PanicData    `json:"panic,omitempty"`
+}
+
+// JobAttempt sets the attempt number that [Job] records for a run of a job,
+// such as the number of times a message has been delivered by a queue. The
+// default is 1.
+func JobAttempt(n int) MiddlewareOption {
+	return func(o *option) {
+		o.attempt = n
+	}
+}
+
+// Job runs fn with a new freeform log entry in its context, and prints the log
+// entry once fn returns, bringing the single log line per unit of work that the
+// [Middleware] gives HTTP handlers to cron jobs and queue consumers. The job's
+// name, attempt number and duration are written under the "@job" key. If fn
+// returns an error, its message is written there too, the log entry's level is
+// set to ERROR, and the error is returned.
+//
+// The options configure the log entry and how it is printed, as they do for
+// the [Middleware]. If fn panics, the panic is written under the "@job" key,
+// the log entry's level is set to ERROR, and the log entry is printed before
+// the panic continues. If the [WithRecovery] option is used, the panic is
+// instead returned as an error.
+func Job(ctx context.Context, name string, fn func(context.Context) error, opts ...MiddlewareOption) error {
+	opt := applyOptions(opts...)
+
+	var options = func(o *option) {
+		*o = opt
+	}
+
+	ctx = AddEntry(ctx, options)
+
+	data := JobData{Name: name, Attempt: max(opt.attempt, 1)}
+	start := opt.timer.Now()
+	v, p, err := call(ctx, fn)
+	data.Duration = opt.timer.Since(start)
+
+	if err != nil {
+		data.Error = err.Error()
+		Error(ctx)
+	}
+
+	if p != nil {
+		data.Panic = p
+		Error(ctx)
+	}
+
+	Add(ctx, "@job", data)
+	Print(ctx, options)
+	releaseEntry[FreeformEntry](ctx)
+
+	if p != nil {
+		if !opt.recovery {
+			panic(v)
+		}
+		err = fmt.Errorf("job %s panicked: %v", name, v)
+	}
+
+	return err
+}
+
+// call calls fn, recovering from any panic. The function returns the panic's
+// value and data describing it, or the error returned by fn.
+func call(ctx context.Context, fn func(context.Context) error) (v any, p *PanicData, err error) {
+	defer func() {
+		if v = recover(); v != nil {
+			stack := make([]byte, 64<<10)
+			stack = stack[:runtime.Stack(stack, false)]
+			p = &PanicData{Value: fmt.Sprint(v), Stack: string(stack)}
+		}
+	}()
+
+	return nil, nil, fn(ctx)
+}
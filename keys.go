@@ -0,0 +1,128 @@
+package logs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CollisionPolicy determines what happens when a key added to a freeform log
+// entry using dot notation crosses a value that is not a map. For example,
+// adding "user.name" when "user" holds a string.
+type CollisionPolicy int
+
+const (
+	// CollisionOverwrite replaces the existing value with a map. This is the
+	// default.
+	CollisionOverwrite CollisionPolicy = iota
+	// CollisionError leaves the log entry unchanged, and makes functions like
+	// [AddE] return an error wrapping [ErrKeyCollision].
+	CollisionError
+	// CollisionRename moves the existing value to a key with a numbered
+	// suffix, such as "user_1", before replacing it with a map.
+	CollisionRename
+)
+
+// WithCollisionPolicy configures the policy that [Add], [AddE], [AddLazy],
+// [AddStruct] and the end of an [AddChild] scope follow when a key crosses a
+// value that is not a map. The default is [CollisionOverwrite].
+func WithCollisionPolicy(policy CollisionPolicy) Option {
+	return func(o *option) {
+		o.collisions = policy
+	}
+}
+
+// WithKeyNormalizer configures printing to pass every key of the log entry,
+// at every depth, through fn, such as [SnakeCase] or strings.ToLower. If two
+// keys normalize to the same key, a key that is already normalized wins, and
+// otherwise the first in sorted order. The log entry itself is not modified.
+func WithKeyNormalizer(fn func(key string) string) PrintOption {
+	return func(o *option) {
+		o.keyNormalizer = fn
+	}
+}
+
+// SnakeCase converts a key to snake_case, for use with [WithKeyNormalizer].
+// Words are split at changes of case and at hyphens and spaces, so "userID",
+// "HTTPStatus" and "request-id" become "user_id", "http_status" and
+// "request_id". A leading "@" is kept.
+func SnakeCase(key string) string {
+	runes := []rune(key)
+
+	var b strings.Builder
+	b.Grow(len(key) + 4)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ':
+			b.WriteByte('_')
+		case unicode.IsUpper(r):
+			if i > 0 && wordStart(runes, i) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// wordStart reports whether the upper case rune at i starts a new word: it
+// follows a lower case letter or digit, or it ends a run of upper case letters
+// and is followed by a lower case letter.
+func wordStart(runes []rune, i int) bool {
+	prev := runes[i-1]
+	if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+		return true
+	}
+
+	return unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+}
+
+// normalizeKeys returns a copy of the map with its keys, and those of its
+// nested maps, passed through fn.
+func normalizeKeys(m map[string]any, fn func(string) string) map[string]any {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	normalized := make(map[string]any, len(m))
+	for _, k := range keys {
+		nk := fn(k)
+		if _, dup := normalized[nk]; dup && nk != k {
+			continue
+		}
+		normalized[nk] = normalizeValue(m[k], fn)
+	}
+
+	return normalized
+}
+
+// normalizeValue normalizes the keys of a map, or of the maps within a slice.
+func normalizeValue(v any, fn func(string) string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return normalizeKeys(v, fn)
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeValue(item, fn)
+		}
+	}
+
+	return v
+}
+
+// renamedKey finds a free key for a value that is moved aside by
+// [CollisionRename].
+func renamedKey(m map[string]any, key string) string {
+	for i := 1; ; i++ {
+		renamed := key + "_" + strconv.Itoa(i)
+		if _, exists := m[renamed]; !exists {
+			return renamed
+		}
+	}
+}
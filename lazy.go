@@ -0,0 +1,24 @@
+package logs
+
+import "encoding/json"
+
+// Lazy is a value whose computation is deferred until the log entry that holds
+// it is printed. Use it for values that are expensive to compute, such as a
+// dump of a large struct or a count from a database, so that they are only
+// computed if the log entry is printed at or above the level for printing.
+//
+//	logs.Add(ctx, "rows", logs.Lazy(func() any { return countRows(db) }))
+//
+// The function is called each time the log entry is printed, and its result
+// is marshaled to JSON in place of the Lazy value.
+type Lazy func() any
+
+// MarshalJSON implements [json.Marshaler] by marshaling the result of the
+// function.
+func (l Lazy) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(l())
+}
@@ -0,0 +1,119 @@
+package logs
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// LevelVar is a log level that can be changed while the application is
+// running, such as to temporarily print DEBUG log entries in production. Use
+// [WithLevelVar] or [PrintLevelVar] to print log entries at or above its level.
+// It is safe for concurrent use. The zero value is INFO.
+type LevelVar struct {
+	// offset is stored relative to INFO so that the zero value is INFO.
+	offset atomic.Int64
+}
+
+// NewLevelVar creates a [LevelVar] set to the level.
+func NewLevelVar(level Level) *LevelVar {
+	v := new(LevelVar)
+	v.Set(level)
+	return v
+}
+
+// Level returns the current level.
+func (v *LevelVar) Level() Level {
+	return Level(v.offset.Load()) + INFO
+}
+
+// Set changes the level.
+func (v *LevelVar) Set(level Level) {
+	v.offset.Store(int64(level - INFO))
+}
+
+func (v *LevelVar) String() string {
+	return v.Level().String()
+}
+
+// MarshalText implements [encoding.TextMarshaler] using the level's name.
+func (v *LevelVar) MarshalText() ([]byte, error) {
+	return []byte(v.Level().String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler] by parsing a level's
+// name using [ParseLevel].
+func (v *LevelVar) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+
+	v.Set(level)
+	return nil
+}
+
+// WithLevelVar sets the log level for printing the log entry to the current
+// level of v, so that it can be changed while the application is running. It
+// takes precedence over [WithLevel].
+func WithLevelVar(v *LevelVar) PrintOption {
+	return func(o *option) {
+		o.levelVar = v
+	}
+}
+
+// PrintLevelVar sets the minimum log level for printing log entries produced
+// by the [Middleware] to the current level of v, as described by
+// [WithLevelVar].
+func PrintLevelVar(v *LevelVar) MiddlewareOption {
+	return MiddlewareOption(WithLevelVar(v))
+}
+
+// minLevel is the minimum level of the log entries to print.
+func (o option) minLevel() Level {
+	if o.levelVar != nil {
+		return o.levelVar.Level()
+	}
+
+	return o.printLevel
+}
+
+// levelBody is the body of requests to and responses from a [LevelHandler].
+type levelBody struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler creates an HTTP handler that lets operators read and change the
+// level of v. A GET request responds with the current level as JSON, such as
+// {"level":"INFO"}. A PUT request with a body of the same form changes the
+// level, and responds with the new level. Levels are parsed using
+// [ParseLevel]. The handler does no authorization of its own, so it should
+// only be served where operators can reach it.
+func LevelHandler(v *LevelVar) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body levelBody
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			level, err := ParseLevel(body.Level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			v.Set(level)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(levelBody{Level: v.Level().String()})
+	})
+}
@@ -1,38 +1,177 @@
 package logs
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 )
 
 // Logger is a logger that logs structured data.
 type Logger[T any] struct {
-	create EntryMaker[T]
+	create     EntryMaker[T]
+	hooks      []func(Level, *T) error
+	validators []Validator[T]
 }
 
 // NewLogger creates a new structured logger for the logs of the specified
-// type.
-func NewLogger[T any](create EntryMaker[T]) Logger[T] {
-	return Logger[T]{create}
+// type. Any validators are registered on every log entry that the logger
+// creates, as described by [WithValidator].
+func NewLogger[T any](create EntryMaker[T], validators ...Validator[T]) Logger[T] {
+	return Logger[T]{create: create, validators: validators}
 }
 
-// AddEntry adds a log entry to the context.
+// MustNewLogger creates a new structured logger for the logs of the specified
+// type, like [NewLogger]. It first checks that the EntryMaker returns a non-nil
+// log entry that can be marshaled to JSON, and panics if it does not. Use this
+// to surface misconfiguration when your application starts rather than when
+// it first prints a log.
+func MustNewLogger[T any](create EntryMaker[T], validators ...Validator[T]) Logger[T] {
+	if create == nil {
+		panic(fmt.Sprintf("logs: EntryMaker for %T is nil", *new(T)))
+	}
+
+	e := create()
+	if e == nil {
+		panic(fmt.Sprintf("logs: EntryMaker for %T returned nil", *new(T)))
+	}
+
+	if _, err := json.Marshal(e); err != nil {
+		panic(fmt.Sprintf("logs: log entry of type %T cannot be marshaled to JSON: %v", *e, err))
+	}
+
+	return NewLogger(create, validators...)
+}
+
+// WithBound creates a copy of the logger that applies the bound functions to
+// every log entry it creates, after the logger's [EntryMaker]. Use this to bind
+// fields, such as a request ID, to all of the log entries that a logger
+// creates.
+func (logger Logger[T]) WithBound(fns ...func(*T)) Logger[T] {
+	create := logger.create
+	logger.create = func() *T {
+		e := create()
+		for _, fn := range fns {
+			fn(e)
+		}
+		return e
+	}
+	return logger
+}
+
+// AddEntry adds a log entry to the context. A logger returned by [For] uses
+// the logger in the context, if there is one, to create the log entry.
 func (logger Logger[T]) AddEntry(ctx context.Context, opts ...Option) context.Context {
+	if logger.create == nil {
+		if l := Get[T](ctx); l != nil && l.create != nil {
+			return l.AddEntry(ctx, opts...)
+		}
+		logger.create = newEntry[T]
+	}
+
+	if len(logger.hooks) > 0 || len(logger.validators) > 0 {
+		opts = append([]Option{func(o *option) {
+			for _, hook := range logger.hooks {
+				o.hooks = append(o.hooks, hook)
+			}
+			for _, validator := range logger.validators {
+				o.validators = append(o.validators, validator)
+			}
+		}}, opts...)
+	}
+
 	return addEntry(ctx, logger.create, opts...)
 }
 
+// newEntry creates an empty log entry, for a logger that has no [EntryMaker].
+func newEntry[T any]() *T {
+	e := new(T)
+	if f, ok := any(e).(*FreeformEntry); ok {
+		*f = FreeformEntry{}
+	}
+
+	return e
+}
+
 // Adjust mutates the log entry in the context as JSON. The function will return
 // false if no log entry of the correct type is found in the context.
-func (Logger[T]) Adjust(ctx context.Context, fns ...adjuster[T]) bool {
+func (Logger[T]) Adjust(ctx context.Context, fns ...Adjuster[T]) bool {
 	return adjust(ctx, fns...)
 }
 
+// SetField sets a field of the log entry in the context, using dot notation to
+// reach nested fields, such as "User.Name". Each part of the key may be either
+// a field's name or the name in its json struct tag, and may pass through
+// pointers to structs and maps with string keys, which are allocated if they
+// are nil. The value must be assignable or, for numbers, convertible to the
+// field's type. A nil value sets the field to its zero value. The function
+// will return false if no log entry of the correct type is found in the
+// context, or if the field cannot be set.
+func (Logger[T]) SetField(ctx context.Context, key string, value any) bool {
+	return setField[T](ctx, key, value)
+}
+
+// AdjustE mutates the log entry in the context, like [Logger.Adjust], but
+// returns an error describing why the log entry could not be changed:
+// [ErrNoEntry] if no log entry of the correct type is found, or [ErrFinalized]
+// if it has been finalized.
+func (Logger[T]) AdjustE(ctx context.Context, fns ...Adjuster[T]) error {
+	return adjustE(ctx, fns...)
+}
+
+// AdjustChanged mutates the log entry in the context and reports whether the
+// mutation changed the log entry. The first return value is true if the log
+// entry changed, and the second is true if a log entry of the correct type was
+// found in the context.
+//
+// Changes are detected by marshaling the log entry to JSON before and after
+// the mutation, which adds the cost of two marshals to the adjustment. Changes
+// to fields that are not marshaled to JSON are not detected.
+func (Logger[T]) AdjustChanged(ctx context.Context, fn Adjuster[T]) (bool, bool) {
+	entry := getMutableEntry[T](ctx)
+	if entry == nil {
+		return false, false
+	}
+
+	before, err := json.Marshal(entry.data)
+	fn(entry.data)
+	if err != nil {
+		return true, true
+	}
+
+	after, err := json.Marshal(entry.data)
+	if err != nil {
+		return true, true
+	}
+
+	return !bytes.Equal(before, after), true
+}
+
 // Print prints the log entry in the context as JSON. The function will return
 // false if no log entry of the correct type is found in the context.
 func (Logger[T]) Print(ctx context.Context, opts ...PrintOption) bool {
 	return print[T](ctx, opts...)
 }
 
+// PrintE prints the log entry in the context, like [Logger.Print], but returns
+// an error describing why the log entry was not printed: [ErrNoEntry] if no
+// log entry of the correct type is found, an error wrapping [ErrNotPrinted] if
+// it was intentionally not printed, or an error describing a failure to
+// marshal or write it.
+func (Logger[T]) PrintE(ctx context.Context, opts ...PrintOption) error {
+	return printE[T](ctx, opts...)
+}
+
+// Finalize prints the log entry in the context, unless it has already been
+// printed, and marks it as finalized. Any later changes to a finalized log
+// entry using [Logger.Adjust] are ignored, and a diagnostic message is written
+// to os.Stderr. The function will return false if no log entry of the correct
+// type is found in the context, or if it was not printed by this call.
+func (Logger[T]) Finalize(ctx context.Context, opts ...PrintOption) bool {
+	return finalize[T](ctx, opts...)
+}
+
 // GetEntry gets the log entry from the context for direct manipulation. The
 // function will return nil if no log entry of the correct type is found in the
 // context.
@@ -44,6 +183,31 @@ func (Logger[T]) GetEntry(ctx context.Context) *T {
 	return nil
 }
 
+// Snapshot returns a deep copy of the log entry in the context, as it is at
+// the time of the call, so that it can be kept or passed elsewhere without
+// being affected by later changes. The copy is made as described by [Detach].
+// The function will return false if no log entry of the correct type is found
+// in the context.
+func (Logger[T]) Snapshot(ctx context.Context) (*T, bool) {
+	return snapshot[T](ctx)
+}
+
+// Merge folds another log entry into the log entry in the context, such as the
+// log entry of a detached goroutine, using fn to decide how their fields
+// combine. fn receives a deep copy of the other log entry, made as described
+// by [Detach], so it may keep references to its data. The function will return
+// false if no log entry of the correct type is found in the context, or if
+// other is nil.
+func (Logger[T]) Merge(ctx context.Context, other *T, fn MergeFunc[T]) bool {
+	return merge(ctx, other, fn)
+}
+
+// Trace sets the log entry's level to TRACE. The function will return false if
+// no log entry of the correct type is found in the context.
+func (Logger[T]) Trace(ctx context.Context) bool {
+	return trace[T](ctx)
+}
+
 // Debug sets the log entry's level to DEBUG and adds data to it. The function
 // will return false if no log entry of the correct type is found in the
 // context.
@@ -79,8 +243,34 @@ func (Logger[T]) Fatal(ctx context.Context) bool {
 	return fatal[T](ctx)
 }
 
+// SetLevel sets the log entry's level to any level, including custom levels
+// created using [RegisterLevel]. The function will return false if no log
+// entry of the correct type is found in the context.
+func (Logger[T]) SetLevel(ctx context.Context, level Level) bool {
+	return setLevel[T](ctx, level)
+}
+
+// Suppress prevents the log entry from being printed, as described by
+// [Suppress]. The function will return false if no log entry of the correct
+// type is found in the context.
+func (Logger[T]) Suppress(ctx context.Context) bool {
+	return suppress[T](ctx)
+}
+
+// Force ensures that the log entry is printed regardless of its level or
+// sampling, as described by [Force]. The function will return false if no log
+// entry of the correct type is found in the context.
+func (Logger[T]) Force(ctx context.Context) bool {
+	return force[T](ctx)
+}
+
 // Middleware adds structured, context-based logging to an HTTP handler. All
 // requests will include a log entry in their context of the requested type.
+// HTTP data is written into freeform log entries under the "@http" key, and
+// passed to log entries of a custom type that implements [HttpDataReceiver]. If
+// [WithRecovery] is used, a log entry for a request whose handler panics has
+// its level set to ERROR. The panic is written into freeform log entries under
+// the "@panic" key, but not into log entries of a custom type.
 func (logger Logger[T]) Middleware(opts ...MiddlewareOption) func(http.Handler) http.Handler {
 	opt := applyOptions(opts...)
 
@@ -88,12 +278,71 @@ func (logger Logger[T]) Middleware(opts ...MiddlewareOption) func(http.Handler)
 		*o = opt
 	}
 
+	_, receives := any(new(T)).(HttpDataReceiver)
+	_, freeform := any(new(T)).(*FreeformEntry)
+	wantsData := receives || freeform
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := logger.Set(r.Context())
+			if opt.skips(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// A layer nested within another middleware joins the existing log
+			// entry, leaving the outermost middleware to print it.
+			if opt.layer != "" && getEntry[T](r.Context()) != nil {
+				start := opt.timer.Now()
+				next.ServeHTTP(w, r)
+				recordLayer[T](r.Context(), opt.layer, opt.timer.Since(start))
+				return
+			}
+
+			r = withRequestID(w, r, opt)
+			r = withRequestContext(r, opt)
+			r = withTraceHeaders(r, opt)
+			// A logger returned by For leaves any logger that the application
+			// placed in the context in place, and uses it to create the log
+			// entry if it is of the same type.
+			ctx := r.Context()
+			if logger.create != nil || ctx.Value(lKey) == nil {
+				ctx = logger.Set(ctx)
+			}
 			ctx = logger.AddEntry(ctx, options)
-			next.ServeHTTP(w, r.WithContext(ctx))
-			logger.Print(ctx, options)
+
+			if !wantsData && opt.layer == "" && !opt.recovery && opt.statusLevels == nil && !opt.statusClasses && opt.emitWhen == nil {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				logger.Print(ctx, options)
+				releaseEntry[T](ctx)
+				return
+			}
+
+			capture := startCapture(w, r, opt)
+			if p := serve(next, capture.w, r.WithContext(ctx), opt); p != nil {
+				logger.Error(ctx)
+				if freeform {
+					Add(ctx, "@panic", p)
+				}
+			}
+
+			data := capture.finish()
+			escalate[T](ctx, data.Status, opt)
+			if opt.layer != "" {
+				recordLayer[T](ctx, opt.layer, data.Duration)
+			}
+
+			if freeform {
+				Add(ctx, "@http", data)
+			} else if receives {
+				logger.Adjust(ctx, func(e *T) {
+					any(e).(HttpDataReceiver).SetHttpData(data)
+				})
+			}
+
+			if emits[T](ctx, data.Status, data.Duration, opt) {
+				logger.Print(ctx, options)
+			}
+			releaseEntry[T](ctx)
 		})
 	}
 }
@@ -1,10 +1,20 @@
 package logs_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	"github.com/rclark/logs"
@@ -102,3 +112,887 @@ func ExampleLogger_Middleware() {
 	middleware(handler).ServeHTTP(w, r)
 	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":42,"flag":true,"messages":["hello","world"]}
 }
+
+func ExampleWithLevelCounter() {
+	counts := map[logs.Level]int{}
+
+	printOptions := []logs.PrintOption{
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithLevel(logs.INFO),
+		logs.WithOutput(io.Discard),
+		logs.WithLevelCounter(func(l logs.Level) {
+			counts[l]++
+		}),
+	}
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+	logger.Print(ctx, printOptions...)
+
+	ctx = logger.AddEntry(context.Background())
+	logger.Debug(ctx)
+	logger.Print(ctx, printOptions...)
+
+	ctx = logger.AddEntry(context.Background())
+	logger.Error(ctx)
+	logger.Print(ctx, printOptions...)
+
+	ctx = logger.AddEntry(context.Background())
+	logger.Error(ctx)
+	logger.Print(ctx, printOptions...)
+
+	fmt.Println(counts[logs.DEBUG], counts[logs.INFO], counts[logs.ERROR])
+	// Output: 0 1 2
+}
+
+type requestLog struct {
+	Name string        `json:"name"`
+	HTTP logs.HttpData `json:"http"`
+}
+
+func (l *requestLog) SetHttpData(data logs.HttpData) {
+	l.HTTP = data
+}
+
+func newRequestLog() *requestLog {
+	return &requestLog{}
+}
+
+var requestLogHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if _, err := io.ReadAll(r.Body); err != nil {
+		http.Error(w, "failed to read body", http.StatusInternalServerError)
+	}
+
+	logs.Get[requestLog](r.Context()).Adjust(r.Context(), func(e *requestLog) {
+		e.Name = "test"
+	})
+})
+
+func ExampleHttpDataReceiver_bodyAndHeaders() {
+	logger := logs.NewLogger(newRequestLog)
+
+	middleware := logger.Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithBody(),
+		logs.WithHeaders("X-Header"),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("bar"))
+	r.Header.Set("X-Header", "x")
+	r.Header.Set("Y-Header", "y")
+
+	middleware(requestLogHandler).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","http":{"method":"POST","path":"/path","headers":{"X-Header":"x"},"body":"bar","status":200,"response_bytes":0,"duration":1234}}
+}
+
+type accessLog struct {
+	Name     string        `json:"name"`
+	Route    string        `json:"route"`
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (l *accessLog) SetHttpData(data logs.HttpData) {
+	l.Route = data.Method + " " + data.Path
+	l.Status = data.Status
+	l.Duration = data.Duration
+}
+
+func ExampleHttpDataReceiver() {
+	logger := logs.NewLogger(func() *accessLog { return &accessLog{} })
+
+	middleware := logger.Middleware(logs.WithTiming(time.Time{}, time.Duration(1234)))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.Get[accessLog](r.Context()).Adjust(r.Context(), func(e *accessLog) {
+			e.Name = "test"
+		})
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+
+	middleware(handler).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","route":"POST /users","status":201,"duration":1234}
+}
+
+type layeredLog struct {
+	Route  string                   `json:"route"`
+	Timing map[string]time.Duration `json:"timing"`
+}
+
+func (l *layeredLog) SetHttpData(data logs.HttpData) {
+	l.Route = data.Method + " " + data.Path
+}
+
+func (l *layeredLog) SetLayerTiming(name string, d time.Duration) {
+	if l.Timing == nil {
+		l.Timing = map[string]time.Duration{}
+	}
+	l.Timing[name] = d
+}
+
+func ExampleLayerTimingReceiver() {
+	logger := logs.NewLogger(func() *layeredLog { return &layeredLog{} })
+
+	timing := logs.WithTiming(time.Time{}, time.Duration(1234))
+	outer := logger.Middleware(timing, logs.WithLayerTiming("outer"))
+	inner := logger.Middleware(timing, logs.WithLayerTiming("inner"))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+
+	outer(inner(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))).ServeHTTP(w, r)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","route":"GET /users","timing":{"inner":1234,"outer":1234}}
+}
+
+func ExampleWithFieldAllowlist_customType() {
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+		e.Count = 42
+		e.Messages = []string{"hello", "world"}
+	})
+
+	logger.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithFieldAllowlist("name", "messages"),
+	)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","messages":["hello","world"],"name":"test"}
+}
+
+type unserializableLog struct {
+	Done chan struct{} `json:"done"`
+}
+
+func ExampleMustNewLogger() {
+	logger := logs.MustNewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+
+	defer func() {
+		fmt.Println(recover())
+	}()
+
+	logs.MustNewLogger(func() *unserializableLog {
+		return &unserializableLog{}
+	})
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false}
+	// logs: log entry of type logs_test.unserializableLog cannot be marshaled to JSON: json: unsupported type: chan struct {}
+}
+
+func ExampleWithOmitZero() {
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+	})
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOmitZero())
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":0,"flag":false}
+}
+
+// requestInfo is unexported, but its fields are promoted into embeddedLog.
+type requestInfo struct {
+	Route  string `json:"route"`
+	Status int    `json:"status"`
+}
+
+type embeddedLog struct {
+	requestInfo
+	Name string `json:"name"`
+}
+
+func ExampleWithOmitZero_embedded() {
+	logger := logs.NewLogger(func() *embeddedLog {
+		return &embeddedLog{}
+	})
+
+	ctx := logger.AddEntry(context.Background())
+
+	logger.Adjust(ctx, func(e *embeddedLog) {
+		e.Route = "/users"
+	})
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOmitZero())
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","route":"/users"}
+}
+
+func ExampleWithMarshalFallback() {
+	logger := logs.NewLogger(func() *unserializableLog {
+		return &unserializableLog{}
+	})
+
+	ctx := logger.AddEntry(context.Background())
+
+	logger.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithMarshalFallback(func(data any) []byte {
+			return []byte(fmt.Sprintf("%T", data))
+		}),
+	)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@fallback":"*logs_test.unserializableLog"}
+}
+
+func ExampleCarryEntry() {
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.Set(context.Background())
+	ctx = logger.AddEntry(ctx)
+
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+	})
+
+	fresh := logs.CarryEntry(ctx, context.Background())
+
+	logs.Get[logs.ExampleLog](fresh).Adjust(fresh, func(e *logs.ExampleLog) {
+		e.Count = 42
+	})
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":42,"flag":false}
+}
+
+func ExampleLogger_Merge() {
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "import"
+	})
+
+	detached := logs.Detach(ctx)
+	logger.Adjust(detached, func(e *logs.ExampleLog) {
+		e.Count = 42
+		e.Messages = []string{"imported"}
+	})
+
+	result, _ := logger.Snapshot(detached)
+	logger.Merge(ctx, result, func(into, from *logs.ExampleLog) {
+		into.Count += from.Count
+		into.Messages = append(into.Messages, from.Messages...)
+	})
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"import","count":42,"flag":false,"messages":["imported"]}
+}
+
+type leveledLog struct {
+	logs.ExampleLog
+	Err string `json:"err,omitempty"`
+}
+
+func (l *leveledLog) Level() logs.Level {
+	if l.Err != "" {
+		return logs.ERROR
+	}
+
+	return logs.INFO
+}
+
+func ExampleLeveler() {
+	logger := logs.NewLogger(func() *leveledLog { return &leveledLog{} })
+
+	ctx := logger.AddEntry(context.Background())
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+
+	logger.Adjust(ctx, func(e *leveledLog) {
+		e.Err = "failed"
+	})
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+
+	logger.Warn(ctx)
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false}
+	// {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false,"err":"failed"}
+	// {"@level":"WARN","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false,"err":"failed"}
+}
+
+func ExampleLogger_WithBound() {
+	logger := logs.NewLogger(logs.NewExampleLog).WithBound(func(e *logs.ExampleLog) {
+		e.Name = "bound"
+	})
+
+	ctx := logger.AddEntry(context.Background())
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+
+	ctx = logger.AddEntry(context.Background())
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Count = 42
+	})
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"bound","count":0,"flag":false}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"bound","count":42,"flag":false}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func TestWithOncePrint(t *testing.T) {
+	logger := logs.NewLogger(logs.NewExampleLog)
+	ctx := logger.AddEntry(context.Background())
+
+	out := &syncBuffer{}
+
+	var wg sync.WaitGroup
+	var printed atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if logger.Print(ctx, logs.WithOutput(out), logs.WithOncePrint()) {
+				printed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := printed.Load(); n != 1 {
+		t.Errorf("expected exactly one successful print, got %d", n)
+	}
+
+	if n := strings.Count(out.buf.String(), "\n"); n != 1 {
+		t.Errorf("expected exactly one line, got %d", n)
+	}
+}
+
+func TestMonotonicTimer(t *testing.T) {
+	timer := logs.MonotonicTimer{}
+
+	start := timer.Now()
+	stripped := start.Round(0)
+	if !strings.Contains(start.String(), " m=") {
+		t.Fatalf("expected %s to carry a monotonic clock reading", start)
+	}
+	if strings.Contains(stripped.String(), " m=") {
+		t.Fatalf("expected %s to carry no monotonic clock reading", stripped)
+	}
+
+	// Durations between times that both carry a monotonic clock reading are
+	// measured using it, so the timer's duration falls between two others.
+	before := time.Now()
+	elapsed := timer.Since(start)
+	after := time.Now()
+	if elapsed < before.Sub(start) || elapsed > after.Sub(start) {
+		t.Errorf("expected a duration between %s and %s, got %s", before.Sub(start), after.Sub(start), elapsed)
+	}
+
+	// A start time without a monotonic clock reading is measured using the wall
+	// clock.
+	if d := timer.Since(stripped.Add(time.Hour)); d > -59*time.Minute {
+		t.Errorf("expected a duration of about -1h using the wall clock, got %s", d)
+	}
+}
+
+func ExampleNewChannelWriter() {
+	ch := make(chan map[string]any, 1)
+	w := logs.NewChannelWriter(ch)
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+	})
+
+	logger.Print(ctx, logs.WithOutput(w), logs.WithCurrentTime(time.Time{}))
+	logger.Print(ctx, logs.WithOutput(w), logs.WithCurrentTime(time.Time{}))
+
+	e := <-ch
+	fmt.Println(e["@level"], e["name"])
+	fmt.Println(w.Dropped())
+	// Output:
+	// INFO test
+	// 1
+}
+
+func ExampleWithEnvField() {
+	os.Setenv("LOGS_EXAMPLE_ENV", "staging")
+	defer os.Unsetenv("LOGS_EXAMPLE_ENV")
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+	ctx := logger.AddEntry(context.Background())
+
+	logger.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithEnvField("LOGS_EXAMPLE_ENV", ""),
+		logs.WithEnvField("LOGS_EXAMPLE_UNSET", "@unset"),
+	)
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@env":"staging","name":"","count":0,"flag":false}
+}
+
+func ExampleWithRunID() {
+	logs.SetRunIDGenerator(func() string { return "run-1" })
+	defer logs.SetRunIDGenerator(nil)
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	for i := 0; i < 2; i++ {
+		ctx := logger.AddEntry(context.Background())
+		logger.Adjust(ctx, func(e *logs.ExampleLog) {
+			e.Count = i
+		})
+		logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithRunID())
+	}
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@run_id":"run-1","name":"","count":0,"flag":false}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@run_id":"run-1","name":"","count":1,"flag":false}
+}
+
+func TestWithRunID(t *testing.T) {
+	logs.SetRunIDGenerator(nil)
+	defer logs.SetRunIDGenerator(nil)
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ids := make([]string, 3)
+	for i := range ids {
+		var buf bytes.Buffer
+		ctx := logger.AddEntry(context.Background())
+		logger.Print(ctx, logs.WithOutput(&buf), logs.WithRunID())
+
+		var e map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		ids[i], _ = e["@run_id"].(string)
+	}
+
+	if ids[0] == "" || ids[0] != ids[1] || ids[1] != ids[2] {
+		t.Errorf("expected the same run ID on every entry, got %v", ids)
+	}
+}
+
+func ExampleWithService() {
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithService("api", "1.2.3", "prod"))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@service":"api","@version":"1.2.3","@env":"prod","name":"","count":0,"flag":false}
+}
+
+func TestWithProcessInfo(t *testing.T) {
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	var buf bytes.Buffer
+	ctx := logger.AddEntry(context.Background())
+	logger.Print(ctx, logs.WithOutput(&buf), logs.WithProcessInfo())
+
+	var e map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+
+	if hostname, _ := os.Hostname(); e["@host"] != hostname {
+		t.Errorf("expected host %q, got %v", hostname, e["@host"])
+	}
+	if e["@pid"] != float64(os.Getpid()) {
+		t.Errorf("expected pid %d, got %v", os.Getpid(), e["@pid"])
+	}
+}
+
+func ExampleRegisterLevel() {
+	notice := logs.RegisterLevel(25, "NOTICE")
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+	logger.SetLevel(ctx, notice)
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithLevel(logs.INFO))
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithLevel(logs.WARN))
+
+	ctx = logger.AddEntry(context.Background())
+	logger.Trace(ctx)
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithLevel(logs.TRACE))
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithLevel(logs.DEBUG))
+	// Output:
+	// {"@level":"NOTICE","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false}
+	// {"@level":"TRACE","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false}
+}
+
+func ExampleParseLevel() {
+	logs.RegisterLevel(35, "ALERT")
+
+	for _, name := range []string{"trace", "Info", "alert", "verbose"} {
+		level, err := logs.ParseLevel(name)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(level)
+	}
+	// Output:
+	// TRACE
+	// INFO
+	// ALERT
+	// unknown log level "verbose"
+}
+
+func ExampleFreeformMode() {
+	freeform := logs.FreeformMode()
+	structured := logs.For[logs.ExampleLog]()
+
+	ctx := freeform.AddEntry(context.Background())
+	logs.Add(ctx, "job", "cleanup", "deleted", 3)
+	freeform.Warn(ctx)
+	freeform.Print(ctx, logs.WithCurrentTime(time.Time{}))
+
+	ctx = logs.NewLogger(logs.NewExampleLog).Set(context.Background())
+	ctx = structured.AddEntry(ctx)
+	structured.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+		e.Count = 42
+	})
+	structured.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"@level":"WARN","@time":"0001-01-01T00:00:00Z","deleted":3,"job":"cleanup"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":42,"flag":false}
+}
+
+type signupLog struct {
+	Email    string `json:"email"`
+	Password string `json:"password" logs:"redact"`
+	Card     struct {
+		Number string `json:"number" logs:"redact"`
+		Brand  string `json:"brand"`
+	} `json:"card"`
+}
+
+func ExampleWithRedaction_structTags() {
+	logger := logs.NewLogger(func() *signupLog { return &signupLog{} })
+
+	ctx := logger.AddEntry(context.Background())
+
+	logger.Adjust(ctx, func(e *signupLog) {
+		e.Email = "test@example.com"
+		e.Password = "hunter2"
+		e.Card.Number = "4111111111111111"
+		e.Card.Brand = "visa"
+	})
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithRedaction("email"))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","card":{"brand":"visa","number":"[REDACTED]"},"email":"[REDACTED]","password":"[REDACTED]"}
+}
+
+func ExampleRequestID() {
+	middleware := logs.NewLogger(logs.NewExampleLog).Middleware(
+		logs.Output(io.Discard),
+		logs.WithRequestID(),
+	)
+
+	var id string
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = logs.RequestID(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/path", nil))
+
+	fmt.Println(len(id), id == w.Header().Get("X-Request-Id"))
+	// Output: 36 true
+}
+
+type levelOnlyEncoder struct{}
+
+func (levelOnlyEncoder) Encode(meta logs.Metadata, entry any) ([]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("[%s] %s\n", meta.Level, data)), nil
+}
+
+func ExampleEncoder() {
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	ctx := logger.AddEntry(context.Background())
+
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+	})
+
+	logger.Warn(ctx)
+	logger.Print(ctx, logs.WithEncoder(levelOnlyEncoder{}))
+	// Output: [WARN] {"name":"test","count":0,"flag":false}
+}
+
+type jobLog struct {
+	Job   string         `json:"job"`
+	Error logs.ErrorData `json:"error"`
+}
+
+func ExampleRecordError() {
+	logger := logs.NewLogger(func() *jobLog { return &jobLog{} })
+
+	ctx := logger.AddEntry(context.Background())
+
+	err := fmt.Errorf("job failed: %w", errors.New("timeout"))
+	logger.Adjust(ctx,
+		func(e *jobLog) { e.Job = "cleanup" },
+		logs.RecordError(func(e *jobLog) *logs.ErrorData { return &e.Error }, err),
+	)
+	logger.Error(ctx)
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","job":"cleanup","error":{"message":"job failed: timeout","type":"*fmt.wrapError","chain":[{"message":"timeout","type":"*errors.errorString"}]}}
+}
+
+func ExampleNewAsyncWriter() {
+	w := logs.NewAsyncWriter(os.Stdout, logs.WithQueueSize(16))
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	for _, name := range []string{"first", "second"} {
+		ctx := logger.AddEntry(context.Background())
+		logger.Adjust(ctx, func(e *logs.ExampleLog) {
+			e.Name = name
+		})
+		logger.Print(ctx, logs.WithOutput(w), logs.WithCurrentTime(time.Time{}))
+	}
+
+	if err := w.Close(); err != nil {
+		fmt.Println(err)
+	}
+
+	_, err := w.Write([]byte("{}\n"))
+	fmt.Println(err, w.Dropped())
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"first","count":0,"flag":false}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"second","count":0,"flag":false}
+	// writer is closed 0
+}
+
+// levelTagger is a logs.LevelWriter that tags each log entry with its level.
+type levelTagger struct {
+	io.Writer
+}
+
+func (t levelTagger) WriteLevel(level logs.Level, p []byte) (int, error) {
+	fmt.Fprintf(t.Writer, "[%s] ", level)
+	return t.Write(p)
+}
+
+func ExampleLevelWriter() {
+	w := logs.NewAsyncWriter(levelTagger{os.Stdout})
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "path", "/users")
+	logs.Warn(ctx)
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Time{}),
+		logs.WithOutput(w),
+		logs.WithEncoder(logs.LogfmtEncoder{}),
+	)
+
+	if err := w.Close(); err != nil {
+		fmt.Println(err)
+	}
+	// Output: [WARN] level=WARN time=0001-01-01T00:00:00Z path=/users
+}
+
+// timeTagger is a logs.MetadataWriter that tags each log entry with its time.
+type timeTagger struct {
+	io.Writer
+}
+
+func (t timeTagger) WriteMetadata(meta logs.Metadata, p []byte) (int, error) {
+	fmt.Fprintf(t.Writer, "[%d] ", meta.Time.Unix())
+	return t.Write(p)
+}
+
+func ExampleMetadataWriter() {
+	w := logs.NewAsyncWriter(timeTagger{os.Stdout})
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Add(ctx, "path", "/users")
+	logs.Print(ctx,
+		logs.WithCurrentTime(time.Unix(1700000000, 0).UTC()),
+		logs.WithOutput(w),
+	)
+
+	if err := w.Close(); err != nil {
+		fmt.Println(err)
+	}
+	// Output: [1700000000] {"@level":"INFO","@time":"2023-11-14T22:13:20Z","path":"/users"}
+}
+
+func ExampleWithLevelOutput() {
+	var errOut bytes.Buffer
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	for _, name := range []string{"ok", "failed"} {
+		ctx := logger.AddEntry(context.Background())
+		logger.Adjust(ctx, func(e *logs.ExampleLog) {
+			e.Name = name
+		})
+		if name == "failed" {
+			logger.Error(ctx)
+		}
+
+		logger.Print(ctx,
+			logs.WithCurrentTime(time.Time{}),
+			logs.WithOutput(os.Stdout),
+			logs.WithLevelOutput(logs.ERROR, &errOut),
+		)
+	}
+
+	fmt.Print("errors: ", errOut.String())
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"ok","count":0,"flag":false}
+	// errors: {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","name":"failed","count":0,"flag":false}
+}
+
+func ExampleLogger_WithHooks() {
+	logger := logs.NewLogger(logs.NewExampleLog).WithHooks(
+		func(level logs.Level, e *logs.ExampleLog) error {
+			if e.Name == "" {
+				return errors.New("log entry has no name")
+			}
+			return nil
+		},
+		func(level logs.Level, e *logs.ExampleLog) error {
+			if level < logs.WARN && e.Count == 0 {
+				return logs.ErrSkipEntry
+			}
+			e.Flag = level >= logs.ERROR
+			return nil
+		},
+	)
+
+	for _, count := range []int{0, 1} {
+		ctx := logger.AddEntry(context.Background())
+		logger.Adjust(ctx, func(e *logs.ExampleLog) {
+			e.Name = "test"
+			e.Count = count
+		})
+		fmt.Println(logger.Print(ctx, logs.WithCurrentTime(time.Time{})))
+	}
+
+	ctx := logger.AddEntry(context.Background(), logs.WithDefaultLevel(logs.ERROR))
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+	})
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// false
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":1,"flag":false}
+	// true
+	// {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","name":"test","count":0,"flag":true}
+}
+
+func ExampleWithDefaults() {
+	logger := logs.NewLogger(logs.NewExampleLog)
+
+	defaults := logs.WithDefaults(func(e *logs.ExampleLog) {
+		e.Name = "api"
+		e.Messages = []string{"started"}
+	})
+
+	ctx := logger.AddEntry(context.Background(), defaults)
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Count = 42
+	})
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"api","count":42,"flag":false,"messages":["started"]}
+}
+
+func ExampleWithStatusLevels() {
+	middleware := logs.NewLogger(logs.NewExampleLog).Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		logs.WithStatusLevels(map[int]logs.Level{http.StatusTooManyRequests: logs.WARN}),
+	)
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/path", nil))
+	// Output: {"@level":"WARN","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false}
+}
+
+type userLog struct {
+	User *struct {
+		Name  string `json:"name"`
+		Admin bool   `json:"admin"`
+	} `json:"user,omitempty"`
+	Attempts int               `json:"attempts"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+func ExampleLogger_SetField() {
+	logger := logs.NewLogger(func() *userLog { return &userLog{} })
+	ctx := logger.AddEntry(context.Background())
+
+	logger.SetField(ctx, "User.Name", "bob")
+	logger.SetField(ctx, "user.admin", true)
+	logger.SetField(ctx, "attempts", 3)
+	logger.SetField(ctx, "Tags.region", "us-east-1")
+	fmt.Println(logger.SetField(ctx, "User.Email", "bob@example.com"))
+	fmt.Println(logger.SetField(ctx, "Attempts", "three"))
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// false
+	// false
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","user":{"name":"bob","admin":true},"attempts":3,"tags":{"region":"us-east-1"}}
+}
+
+type canonicalLog struct {
+	RequestID  string `json:"request_id"`
+	CustomerID string `json:"customer_id"`
+}
+
+func ExampleNewLogger_validator() {
+	logger := logs.NewLogger(
+		func() *canonicalLog { return &canonicalLog{} },
+		func(e *canonicalLog) error {
+			var errs []error
+			if e.RequestID == "" {
+				errs = append(errs, errors.New("request_id is required"))
+			}
+			if e.CustomerID == "" {
+				errs = append(errs, errors.New("customer_id is required"))
+			}
+			return errors.Join(errs...)
+		},
+	)
+
+	ctx := logger.AddEntry(context.Background())
+	logger.Adjust(ctx, func(e *canonicalLog) {
+		e.RequestID = "abc123"
+	})
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+
+	logger.Adjust(ctx, func(e *canonicalLog) {
+		e.CustomerID = "cus_42"
+	})
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","@validation_errors":["customer_id is required"],"customer_id":"","request_id":"abc123"}
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","request_id":"abc123","customer_id":"cus_42"}
+}
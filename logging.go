@@ -3,22 +3,34 @@ package logs
 import (
 	"bytes"
 	"context"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/netip"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // EntryMaker is any function that creates a new, mutable log entry.
 type EntryMaker[T any] func() *T
 
-// Level represents the level of logging.
+// Level represents the level of logging. Levels are ordered, so that a log
+// entry is only printed if its level is at least the level configured for
+// printing. Custom levels can be created using [RegisterLevel].
 type Level int
 
 const (
-	// DEBUG is the lowest level of logging for your most verbose information.
-	DEBUG Level = iota
+	// TRACE is the lowest level of logging for extremely detailed information.
+	TRACE Level = iota * 10
+	// DEBUG is for logging your most verbose information.
+	DEBUG
 	// INFO is the default logging level for general information.
 	INFO
 	// WARN is for logging more important information, but not critical.
@@ -29,34 +41,178 @@ const (
 	FATAL
 )
 
-func (l Level) String() string {
+// customLevels holds the names of levels created using [RegisterLevel].
+var customLevels = struct {
+	sync.RWMutex
+	names map[Level]string
+}{names: map[Level]string{}}
+
+// builtinLevelName returns the name of one of the package's built-in levels.
+func builtinLevelName(l Level) (string, bool) {
 	switch l {
+	case TRACE:
+		return "TRACE", true
 	case DEBUG:
-		return "DEBUG"
+		return "DEBUG", true
 	case INFO:
-		return "INFO"
+		return "INFO", true
 	case WARN:
-		return "WARN"
+		return "WARN", true
 	case ERROR:
-		return "ERROR"
+		return "ERROR", true
 	case FATAL:
-		return "FATAL"
+		return "FATAL", true
 	default:
-		return "UNKNOWN"
+		return "", false
 	}
 }
 
+func (l Level) String() string {
+	if name, ok := builtinLevelName(l); ok {
+		return name
+	}
+
+	customLevels.RLock()
+	defer customLevels.RUnlock()
+
+	if name, ok := customLevels.names[l]; ok {
+		return name
+	}
+
+	return "UNKNOWN"
+}
+
+// RegisterLevel creates a custom level with the given value and name. The
+// value determines how the level is ordered relative to the built-in levels,
+// which are spaced 10 apart from TRACE at 0 to FATAL at 50. For example, a
+// NOTICE level between INFO and WARN could be registered as follows:
+//
+//	var NOTICE = logs.RegisterLevel(25, "NOTICE")
+//
+// The name is printed in the "@level" field of log entries and is matched
+// case-insensitively by [ParseLevel]. Registering a level again replaces its
+// name. The function panics if the value or name is already used by a
+// different level.
+func RegisterLevel(value int, name string) Level {
+	l := Level(value)
+	name = strings.ToUpper(name)
+
+	if _, ok := builtinLevelName(l); ok {
+		panic(fmt.Sprintf("logs: level %d is a built-in level", value))
+	}
+
+	if existing, err := ParseLevel(name); err == nil && existing != l {
+		panic(fmt.Sprintf("logs: level name %q is already in use", name))
+	}
+
+	customLevels.Lock()
+	defer customLevels.Unlock()
+
+	customLevels.names[l] = name
+	return l
+}
+
+// ParseLevel finds the level with the given name, which is matched
+// case-insensitively. Both built-in levels and those created using
+// [RegisterLevel] are found. The function will return an error if there is no
+// level with the name.
+func ParseLevel(name string) (Level, error) {
+	for l := TRACE; l <= FATAL; l += 10 {
+		if builtin, _ := builtinLevelName(l); strings.EqualFold(builtin, name) {
+			return l, nil
+		}
+	}
+
+	customLevels.RLock()
+	defer customLevels.RUnlock()
+
+	for l, custom := range customLevels.names {
+		if strings.EqualFold(custom, name) {
+			return l, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
 type option struct {
-	out         io.Writer
-	entryLevel  Level
-	printLevel  Level
-	timer       Timer
-	body        bool
-	allHeaders  bool
-	someHeaders []string
-	now         time.Time
-	since       time.Duration
-	fakeTime    bool
+	out             io.Writer
+	entryLevel      Level
+	printLevel      Level
+	timer           Timer
+	body            bool
+	allHeaders      bool
+	someHeaders     []string
+	now             time.Time
+	since           time.Duration
+	fakeTime        bool
+	counter         func(Level)
+	allowlist       []string
+	layer           string
+	bodyEncoding    BodyEncoding
+	attemptHeader   string
+	clientInfo      bool
+	connInfo        bool
+	trustedProxies  []netip.Prefix
+	attempt         int
+	omitZero        bool
+	ttfb            bool
+	fallback        func(any) []byte
+	human           io.Writer
+	handler         string
+	maxLine         int
+	tenant          func(context.Context) string
+	tenantRoutes    map[string]io.Writer
+	fieldCount      bool
+	once            bool
+	meta            []MetaField
+	emf             *emf
+	emfRecords      []emfRecord
+	caller          bool
+	callerSkip      int
+	callerFunc      bool
+	eagerBody       bool
+	responseHeaders []string
+	recovery        bool
+	redactKeys      []string
+	redactor        func(string, any) (any, bool)
+	keyNormalizer   func(string) string
+	collisions      CollisionPolicy
+	queryArgs       bool
+	argRedactor     func(driver.NamedValue) any
+	requestID       string
+	encoder         Encoder
+	contextFields   []func(context.Context) []MetaField
+	requestContext  []func(*http.Request) context.Context
+	stack           bool
+	sampler         func(context.Context, any) bool
+	rateLimit       *RateLimiter
+	emitWhen        func(int, time.Duration, any) bool
+	emitErrors      bool
+	levelOutputs    []levelOutput
+	levelKey        string
+	timeKey         string
+	timeFormat      string
+	hooks           []any
+	defaults        []any
+	skipPaths       []string
+	skipFunc        func(*http.Request) bool
+	statusLevels    map[int]Level
+	statusClasses   bool
+	pooling         bool
+	fatal           FatalBehavior
+	bodyLimit       int
+	bodySkipTypes   []string
+	responseBody    int
+	levelVar        *LevelVar
+	query           bool
+	route           func(*http.Request) string
+	traceHeaders    bool
+	denylist        []string
+	overflow        Overflow
+	messageKey      string
+	durations       DurationStyle
+	validators      []any
 }
 
 // PrintOption is a configuration option for printing logs.
@@ -78,22 +234,266 @@ func WithLevel(level Level) PrintOption {
 	}
 }
 
+// WithLevelCounter registers a callback that is invoked with the level of each
+// log entry that is printed. Entries that are filtered out by the print level
+// or that fail to print do not invoke the callback. Use this to maintain your
+// own counters of logs by level.
+func WithLevelCounter(fn func(Level)) PrintOption {
+	return func(o *option) {
+		o.counter = fn
+	}
+}
+
+// WithFieldAllowlist configures printing to include only the specified fields
+// of the log entry, plus the "@level" and "@time" fields. Keys may use dot
+// notation to refer to nested fields, and a "*" part of a key matches any
+// field at that level. The log entry itself is not modified.
+func WithFieldAllowlist(keys ...string) PrintOption {
+	return func(o *option) {
+		o.allowlist = append([]string{}, keys...)
+	}
+}
+
+// WithIncludeKeys configures printing to include only the specified fields of
+// the log entry, like [WithFieldAllowlist]. Unlike [WithFieldAllowlist], keys
+// are added to those included by earlier options rather than replacing them.
+func WithIncludeKeys(keys ...string) PrintOption {
+	return func(o *option) {
+		o.allowlist = append(append([]string{}, o.allowlist...), keys...)
+	}
+}
+
+// WithExcludeKeys configures printing to omit the specified fields of the log
+// entry, such as "@http.headers.Cookie" or "debug.*", so that sensitive or
+// noisy fields can be removed from the output. Keys may use dot notation to
+// refer to nested fields, and a "*" part of a key matches any field at that
+// level. Fields are excluded after [WithIncludeKeys] is applied. The log entry
+// itself is not modified.
+func WithExcludeKeys(keys ...string) PrintOption {
+	return func(o *option) {
+		o.denylist = append(append([]string{}, o.denylist...), keys...)
+	}
+}
+
+// WithOmitZero configures printing to omit any fields of a custom log entry
+// type that hold zero values, regardless of the fields' struct tags. The log
+// entry itself is not modified. Only the top-level fields of the log entry,
+// including those of embedded structs, are considered.
+//
+// This option uses reflection to inspect the log entry and requires decoding
+// and re-encoding the marshaled JSON, which adds a cost to every print. It has
+// no effect on a [FreeformEntry].
+func WithOmitZero() PrintOption {
+	return func(o *option) {
+		o.omitZero = true
+	}
+}
+
+// WithFieldCount configures printing to include the number of top-level fields
+// in each log entry under the "@field_count" key. Nested fields are not counted
+// separately, and the "@level", "@time" and "@field_count" fields are not
+// counted. Fields removed by other print options are not counted. The log entry
+// itself is not modified.
+func WithFieldCount() PrintOption {
+	return func(o *option) {
+		o.fieldCount = true
+	}
+}
+
+// WithOncePrint configures printing so that a log entry is only printed once.
+// If the log entry has already been printed with this option, including by
+// another goroutine, printing will return false without writing anything. This
+// is useful when both a handler and a middleware might print the same entry.
+func WithOncePrint() PrintOption {
+	return func(o *option) {
+		o.once = true
+	}
+}
+
+// WithMarshalFallback configures a function to produce an alternative
+// representation of a log entry that fails to marshal to JSON. If the function
+// returns a JSON object, it is printed with the "@level" and "@time" fields.
+// Otherwise its output is printed as a string under the "@fallback" key.
+func WithMarshalFallback(fn func(data any) []byte) PrintOption {
+	return func(o *option) {
+		o.fallback = fn
+	}
+}
+
+// WithDualOutput configures printing to write each log entry twice: as a
+// human-readable line to the human writer, and as JSON to the json writer. The
+// human-readable line is formatted by a [ConsoleEncoder], and the json writer
+// receives the output of any encoder set using [WithEncoder]. This overrides
+// [WithOutput].
+func WithDualOutput(human io.Writer, json io.Writer) PrintOption {
+	return func(o *option) {
+		o.human = human
+		o.out = json
+	}
+}
+
+// WithMaxLineBytes limits the size of each printed line, including the trailing
+// newline, to n bytes. If a log entry would exceed the limit, its largest
+// fields are removed until it fits and the "@truncated" field is set to true.
+// The "@level" and "@time" fields are never removed, and the output is always
+// a complete log entry, so an entry may still exceed the limit if n is very
+// small.
+func WithMaxLineBytes(n int) PrintOption {
+	return func(o *option) {
+		o.maxLine = n
+		o.overflow = 0
+	}
+}
+
+// Overflow determines how printing handles a log entry that exceeds the size
+// set by [WithMaxEntrySize].
+type Overflow int
+
+const (
+	// OverflowDrop removes the log entry's largest fields until it fits, and
+	// lists their keys under the "@truncated" field.
+	OverflowDrop Overflow = iota + 1
+	// OverflowTruncate shortens the log entry's longest string fields until it
+	// fits, ending each with "...", and lists their keys under the
+	// "@truncated" field. If the log entry still does not fit, its largest
+	// fields are removed as with OverflowDrop.
+	OverflowTruncate
+	// OverflowSplit prints the log entry's fields across as many lines as
+	// necessary, each with the same "@level" and "@time" fields. Each line is
+	// marked with its "@part" number, starting at 1, and the total number of
+	// "@parts".
+	OverflowSplit
+)
+
+// WithMaxEntrySize limits the size of each printed line, including the
+// trailing newline, to n bytes, such as the 256KB limit of CloudWatch Logs. The
+// overflow determines how a log entry that exceeds the limit is handled. Like
+// [WithMaxLineBytes], the "@level" and "@time" fields are never removed, so a
+// line may still exceed the limit if n is very small. The log entry itself is
+// not modified.
+func WithMaxEntrySize(n int, overflow Overflow) PrintOption {
+	return func(o *option) {
+		o.maxLine = n
+		o.overflow = overflow
+	}
+}
+
+// WithLevelOutput configures printing to write log entries at or above the
+// level to w, instead of the output set by [WithOutput]. The option may be used
+// more than once, in which case each log entry is written to the output with
+// the highest level that does not exceed the log entry's level. For example,
+// WithLevelOutput(ERROR, os.Stderr) sends ERROR and FATAL log entries to
+// os.Stderr while others go to os.Stdout.
+func WithLevelOutput(level Level, w io.Writer) PrintOption {
+	return func(o *option) {
+		o.levelOutputs = append(o.levelOutputs, levelOutput{level, w})
+	}
+}
+
+// levelOutput is an output for log entries at or above a level.
+type levelOutput struct {
+	level Level
+	out   io.Writer
+}
+
+// outputFor chooses the output for a log entry at the given level.
+func (o option) outputFor(level Level) io.Writer {
+	var chosen *levelOutput
+	for i, lo := range o.levelOutputs {
+		if level >= lo.level && (chosen == nil || lo.level >= chosen.level) {
+			chosen = &o.levelOutputs[i]
+		}
+	}
+
+	if chosen == nil {
+		return o.out
+	}
+
+	return chosen.out
+}
+
+// LevelWriter is implemented by outputs that use the level of each log entry,
+// such as to label or route it. When a log entry is printed to a LevelWriter,
+// its level is passed to WriteLevel, so that the output does not need to read
+// it from the encoded log entry, whose keys and format depend on the encoder
+// and on [WithMetadataKeys].
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level Level, p []byte) (int, error)
+}
+
+// MetadataWriter is implemented by outputs that use more of the [Metadata] of
+// each log entry than its level, such as its time. When a log entry is printed
+// to a MetadataWriter, its metadata is passed to WriteMetadata, which is used
+// in preference to the WriteLevel method of a [LevelWriter].
+type MetadataWriter interface {
+	io.Writer
+	WriteMetadata(meta Metadata, p []byte) (int, error)
+}
+
+// writeLevel writes an encoded log entry to w, passing its level along if w is
+// a [LevelWriter].
+func writeLevel(w io.Writer, level Level, p []byte) (int, error) {
+	if lw, ok := w.(LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+
+	return w.Write(p)
+}
+
+// writeMeta writes an encoded log entry to w, passing its metadata along if w
+// is a [MetadataWriter], or its level if w is a [LevelWriter].
+func writeMeta(w io.Writer, meta Metadata, p []byte) (int, error) {
+	if mw, ok := w.(MetadataWriter); ok {
+		return mw.WriteMetadata(meta, p)
+	}
+
+	return writeLevel(w, meta.Level, p)
+}
+
+// WithTenantRouter configures printing to choose the output for each log entry
+// based on a tenant ID. The tenant function is called with the context at
+// print time and may use the context, or the log entry within it, to resolve
+// the ID. Log entries are written to the route for the tenant ID, or to the
+// fallback if there is no such route. A non-nil fallback overrides
+// [WithOutput], and a nil fallback keeps the configured output.
+//
+// A tenant's route also takes precedence over [WithLevelOutput], so that each
+// tenant's log entries stay together. Log entries without a route are written
+// to the outputs configured by [WithLevelOutput], or to the fallback.
+func WithTenantRouter(tenant func(ctx context.Context) string, routes map[string]io.Writer, fallback io.Writer) PrintOption {
+	return func(o *option) {
+		o.tenant = tenant
+		o.tenantRoutes = routes
+		if fallback != nil {
+			o.out = fallback
+		}
+	}
+}
+
 // Timer is an interface for measuring HTTP request duration. Provide your own
 // implementation to use as a custom timer if you want to test your logging
-// system.
+// system. The default is a [MonotonicTimer]. Use [WithTimer] to provide a
+// custom timer to the middleware, or [WithTiming] and [WithCurrentTime] to use
+// a fake timer that always reports the same time and duration.
 type Timer interface {
 	Now() time.Time
 	Since(time.Time) time.Duration
 }
 
-type defaultTimer struct{}
+// MonotonicTimer is the default [Timer]. It uses time.Now and time.Since, so
+// durations are measured using the monotonic clock reading that the times
+// returned by Now carry, as described by the time package.
+type MonotonicTimer struct{}
 
-func (defaultTimer) Now() time.Time {
+// Now returns the current time.
+func (MonotonicTimer) Now() time.Time {
 	return time.Now()
 }
 
-func (defaultTimer) Since(t time.Time) time.Duration {
-	return time.Since(t)
+// Since returns the time elapsed since start.
+func (MonotonicTimer) Since(start time.Time) time.Duration {
+	return time.Since(start)
 }
 
 type fakeTimer struct {
@@ -130,12 +530,23 @@ func WithDefaultLevel(level Level) Option {
 	}
 }
 
+// WithMessageKey sets the key of the freeform log entry under which [Msg] and
+// [Msgf] write the log entry's message. The default is "message". The key may
+// use dot notation to create a nested field.
+func WithMessageKey(key string) Option {
+	return func(o *option) {
+		o.messageKey = key
+	}
+}
+
 func applyOptions[T ~func(*option)](opts ...T) option {
 	o := option{
 		out:        os.Stdout,
 		entryLevel: INFO,
 		printLevel: INFO,
-		timer:      defaultTimer{},
+		timer:      MonotonicTimer{},
+		encoder:    JSONEncoder{},
+		messageKey: "message",
 	}
 
 	for _, opt := range opts {
@@ -157,15 +568,94 @@ type entryKey struct{}
 var eKey = entryKey{}
 
 type entry[T any] struct {
-	level Level
-	data  *T
+	level      Level
+	leveled    bool
+	printed    atomic.Bool
+	claimed    atomic.Bool
+	finalized  bool
+	hooks      []func(Level, *T) error
+	timer      Timer
+	start      time.Time
+	metrics    sync.Mutex
+	pooled     bool
+	msgKey     string
+	validators []Validator[T]
+	emf        []emfRecord
+	caller     *callSite
+	suppressed bool
+	forced     bool
+	collisions CollisionPolicy
+	data       *T
+}
+
+// Leveler may be implemented by a custom log entry type that determines its own
+// level from its data. If the log entry implements Leveler, its level is used
+// when printing unless the level was explicitly set using a function like
+// [Debug] or [Error].
+type Leveler interface {
+	Level() Level
+}
+
+// currentLevel is the log entry's level at the time of printing.
+func (e *entry[T]) currentLevel() Level {
+	if !e.leveled {
+		if l, ok := any(e.data).(Leveler); ok {
+			return l.Level()
+		}
+	}
+
+	return e.level
+}
+
+// setLevel explicitly sets the log entry's level.
+func (e *entry[T]) setLevel(level Level) {
+	e.level = level
+	e.leveled = true
+	e.caller.capture()
 }
 
 func addEntry[T any](ctx context.Context, create EntryMaker[T], opts ...Option) context.Context {
-	log := entry[T]{data: create()}
 	options := applyOptions(opts...)
+
+	var log *entry[T]
+	if options.pooling {
+		log = acquireEntry(create)
+	} else {
+		log = &entry[T]{data: create()}
+	}
+
 	log.level = options.entryLevel
-	return context.WithValue(ctx, eKey, &log)
+	log.hooks = entryHooks[T](options)
+	log.validators = entryValidators[T](options)
+	log.timer = options.timer
+	log.start = options.timer.Now()
+	log.msgKey = options.messageKey
+	log.collisions = options.collisions
+	log.caller = newCallSite(options)
+	log.caller.capture()
+	applyDefaults(log.data, options)
+	return context.WithValue(ctx, eKey, log)
+}
+
+// Elapsed returns the time elapsed since the log entry in the context was
+// created, measured using its [Timer], whatever the type of the log entry. Use
+// it to record the duration of work, such as an RPC, in the log entry. The
+// function will return false if no log entry is found in the context.
+func Elapsed(ctx context.Context) (time.Duration, bool) {
+	if e, ok := ctx.Value(eKey).(timed); ok {
+		return e.elapsed(), true
+	}
+
+	return 0, false
+}
+
+// timed is implemented by log entries of every type.
+type timed interface {
+	elapsed() time.Duration
+}
+
+func (e *entry[T]) elapsed() time.Duration {
+	return e.timer.Since(e.start)
 }
 
 func getEntry[T any](ctx context.Context) *entry[T] {
@@ -176,52 +666,316 @@ func getEntry[T any](ctx context.Context) *entry[T] {
 	return nil
 }
 
-// adjuster is a function that adjust a log entry.
-type adjuster[T any] func(*T)
+// getMutableEntry gets the log entry from the context so that it can be
+// changed. The function will return nil if no log entry of the correct type is
+// found in the context, or if the log entry has been finalized.
+func getMutableEntry[T any](ctx context.Context) *entry[T] {
+	entry, err := mutableEntry[T](ctx)
+	if errors.Is(err, ErrFinalized) {
+		fmt.Fprintln(os.Stderr, "log entry has been finalized, ignoring changes")
+	}
+
+	return entry
+}
+
+// mismatched reports an option for log entries of another type, such as a
+// hook, which is ignored.
+func mismatched[T any](kind string, v any) {
+	fmt.Fprintf(os.Stderr, "%s of type %T does not match log entry of type %T, ignoring it\n", kind, v, *new(T))
+}
+
+// mutableEntry gets the log entry from the context, unless it has been
+// finalized.
+func mutableEntry[T any](ctx context.Context) (*entry[T], error) {
+	entry := getEntry[T](ctx)
+	if entry == nil {
+		return nil, ErrNoEntry
+	}
+
+	if entry.finalized {
+		return nil, ErrFinalized
+	}
+
+	return entry, nil
+}
+
+// CarryEntry copies the log entry from one context into another, along with any
+// [Logger] that was placed in the context using [Logger.Set]. Use this when
+// work must continue with a fresh context, such as one derived from
+// context.Background, but should still contribute to the same log entry. The
+// function returns the destination context unchanged if the source context has
+// no log entry or logger.
+func CarryEntry(from, to context.Context) context.Context {
+	if e := from.Value(eKey); e != nil {
+		to = context.WithValue(to, eKey, e)
+	}
+
+	if l := from.Value(lKey); l != nil {
+		to = context.WithValue(to, lKey, l)
+	}
+
+	return to
+}
+
+// Adjuster is a function that adjusts a log entry.
+type Adjuster[T any] func(*T)
 
 // adjust mutates the log entry in the context. The function will return false
 // if no log entry of the correct type is found in the context.
-func adjust[T any](ctx context.Context, fns ...adjuster[T]) bool {
-	if entry := getEntry[T](ctx); entry != nil {
-		for _, fn := range fns {
-			fn(entry.data)
-		}
-		return true
+func adjust[T any](ctx context.Context, fns ...Adjuster[T]) bool {
+	return check(adjustE(ctx, fns...))
+}
+
+// adjustE mutates the log entry in the context. The function will return an
+// error if no log entry of the correct type is found in the context, or if it
+// has been finalized.
+func adjustE[T any](ctx context.Context, fns ...Adjuster[T]) error {
+	entry, err := mutableEntry[T](ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range fns {
+		fn(entry.data)
+	}
+
+	return nil
+}
+
+// setField sets a field of the log entry in the context using dot notation. The
+// function will return false if no log entry of the correct type is found in
+// the context, or if the field cannot be set.
+func setField[T any](ctx context.Context, key string, value any) bool {
+	if entry := getMutableEntry[T](ctx); entry != nil {
+		return setStructPath(reflect.ValueOf(entry.data), key, value)
 	}
 
 	return false
 }
 
 func print[T any](ctx context.Context, opts ...PrintOption) bool {
-	if entry := getEntry[T](ctx); entry != nil {
-		options := applyOptions(opts...)
+	return check(printE[T](ctx, opts...))
+}
 
-		if entry.level < options.printLevel {
-			return false
-		}
+// printE prints the log entry in the context. The function will return an
+// error describing why the log entry was not printed.
+func printE[T any](ctx context.Context, opts ...PrintOption) error {
+	entry := getEntry[T](ctx)
+	if entry == nil {
+		return ErrNoEntry
+	}
 
-		data, err := json.Marshal(entry.data)
+	if entry.suppressed {
+		return fmt.Errorf("%w: suppressed", ErrNotPrinted)
+	}
+
+	options := applyOptions(opts...)
+
+	level := entry.currentLevel()
+	if printLevel := options.minLevel(); level < printLevel && !entry.forced {
+		return fmt.Errorf("%w: level %s is below %s", ErrNotPrinted, level, printLevel)
+	}
+
+	if !entry.forced && !options.sampled(ctx, entry.data, level) {
+		return fmt.Errorf("%w: sampled out", ErrNotPrinted)
+	}
+
+	if !entry.forced {
+		limited, err := options.rateLimited(entry.data)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to marshal log entry to JSON: %v\n", err)
-			return false
+			return err
+		}
+		if limited {
+			return fmt.Errorf("%w: rate limited", ErrNotPrinted)
 		}
+	}
+
+	if !options.once {
+		return emit(ctx, entry, level, options)
+	}
+
+	if !entry.claimed.CompareAndSwap(false, true) {
+		return fmt.Errorf("%w: already printed", ErrNotPrinted)
+	}
+
+	if err := emit(ctx, entry, level, options); err != nil {
+		entry.claimed.Store(false)
+		return err
+	}
 
-		if bytes.Index(data, []byte("{")) == 0 {
-			tpl := `{"@level":"%s","@time":"%s",`
-			if bytes.Index(data, []byte("}")) == 1 {
-				tpl = `{"@level":"%s","@time":"%s"`
-			}
-			now := options.timer.Now().Format(time.RFC3339)
-			meta := []byte(fmt.Sprintf(tpl, entry.level, now))
-			data = append(meta, data[1:]...)
-			data = append(data, '\n')
+	return nil
+}
+
+// emit writes the log entry to the configured output. The function will return
+// an error describing why the log entry was not written.
+func emit[T any](ctx context.Context, entry *entry[T], level Level, options option) error {
+	if err := entry.runHooks(level); err != nil {
+		if errors.Is(err, ErrSkipEntry) {
+			return fmt.Errorf("%w: %w", ErrNotPrinted, err)
+		}
+		return fmt.Errorf("log entry rejected by hook: %w", err)
+	}
+
+	meta := Metadata{
+		Level:          level,
+		Time:           options.timer.Now(),
+		Fields:         options.meta,
+		LevelKey:       options.levelKey,
+		TimeKey:        options.timeKey,
+		TimeFormat:     options.timeFormat,
+		DurationFormat: options.durations,
+	}
+	if fields := contextFields(ctx, options.contextFields); fields != nil {
+		meta.Fields = append(append([]MetaField{}, options.meta...), fields...)
+	}
+	if td := TraceContext(ctx); options.traceHeaders && td != nil {
+		meta.Fields = append(append([]MetaField{}, meta.Fields...), MetaField{"@trace", td})
+	}
+	if fields := entry.caller.fields(); fields != nil {
+		meta.Fields = append(append([]MetaField{}, meta.Fields...), fields...)
+	}
+
+	options.out = options.outputFor(level)
+	options.emfRecords = entry.emfRecords()
+
+	if options.tenant != nil {
+		if out, ok := options.tenantRoutes[options.tenant(ctx)]; ok {
+			options.out = out
+		}
+	}
+
+	var written bool
+	if len(entry.validators) == 0 {
+		var err error
+		if written, err = writeFast(entry.data, meta, options); err != nil {
+			return fmt.Errorf("failed to write log entry: %w", err)
 		}
+	}
 
-		if _, err := options.out.Write(data); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to write log entry: %v\n", err)
-			return false
+	if !written {
+		if err := writeEntry(entry, meta, options); err != nil {
+			return err
 		}
+	}
 
+	entry.printed.Store(true)
+
+	if options.counter != nil {
+		options.counter(level)
+	}
+
+	options.terminate(level)
+	return nil
+}
+
+// writeEntry marshals the log entry, applies any print-time adjustments to its
+// fields, and writes it using the configured encoder and outputs.
+func writeEntry[T any](entry *entry[T], meta Metadata, options option) error {
+	data, err := json.Marshal(entry.data)
+	if err != nil && options.fallback != nil {
+		data, err = fallbackJSON(options.fallback(entry.data)), nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to marshal log entry to JSON: %w", err)
+	}
+
+	data = entry.validate(data)
+
+	if data, err = reshape(data, entry.data, options); err != nil {
+		return fmt.Errorf("failed to marshal log entry to JSON: %w", err)
+	}
+
+	var line []byte
+	if options.human != nil {
+		if line, err = (ConsoleEncoder{}).Encode(meta, json.RawMessage(data)); err != nil {
+			return fmt.Errorf("failed to encode log entry: %w", err)
+		}
+	}
+
+	if router, ok := options.out.(*Router); ok {
+		if err := router.route(data, meta, options); err != nil {
+			return fmt.Errorf("failed to write log entry: %w", err)
+		}
+	} else {
+		if data, err = fitLine(data, meta, options); err != nil {
+			return fmt.Errorf("failed to encode log entry: %w", err)
+		}
+
+		if _, err := writeMeta(options.out, meta, data); err != nil {
+			return fmt.Errorf("failed to write log entry: %w", err)
+		}
+	}
+
+	if options.human != nil {
+		if _, err := writeMeta(options.human, meta, line); err != nil {
+			return fmt.Errorf("failed to write log entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// finalize prints the log entry in the context, unless it has already been
+// printed, and prevents any further changes to it. The function will return
+// false if no log entry of the correct type is found in the context, or if the
+// entry was not printed by this call.
+func finalize[T any](ctx context.Context, opts ...PrintOption) bool {
+	if entry := getEntry[T](ctx); entry != nil {
+		printed := false
+		if !entry.printed.Load() && !entry.finalized {
+			printed = print[T](ctx, opts...)
+		}
+
+		entry.finalized = true
+		return printed
+	}
+
+	return false
+}
+
+// fallbackJSON ensures that the output of a marshal fallback function is a JSON
+// object. Any other output is written as a string under the "@fallback" key.
+func fallbackJSON(data []byte) []byte {
+	if bytes.Index(data, []byte("{")) == 0 && json.Valid(data) {
+		return data
+	}
+
+	wrapped, _ := json.Marshal(map[string]string{"@fallback": string(data)})
+	return wrapped
+}
+
+// suppress prevents the log entry in the context from being printed. The
+// function will return false if no log entry of the correct type is found in
+// the context.
+func suppress[T any](ctx context.Context) bool {
+	if entry := getEntry[T](ctx); entry != nil {
+		entry.suppressed, entry.forced = true, false
+		return true
+	}
+
+	return false
+}
+
+// force ensures that the log entry in the context is printed regardless of its
+// level or sampling. The function will return false if no log entry of the
+// correct type is found in the context.
+func force[T any](ctx context.Context) bool {
+	if entry := getEntry[T](ctx); entry != nil {
+		entry.suppressed, entry.forced = false, true
+		return true
+	}
+
+	return false
+}
+
+func trace[T any](ctx context.Context) bool {
+	return setLevel[T](ctx, TRACE)
+}
+
+func setLevel[T any](ctx context.Context, level Level) bool {
+	if entry := getEntry[T](ctx); entry != nil {
+		entry.setLevel(level)
 		return true
 	}
 
@@ -230,7 +984,7 @@ func print[T any](ctx context.Context, opts ...PrintOption) bool {
 
 func debug[T any](ctx context.Context) bool {
 	if entry := getEntry[T](ctx); entry != nil {
-		entry.level = DEBUG
+		entry.setLevel(DEBUG)
 		return true
 	}
 
@@ -239,7 +993,7 @@ func debug[T any](ctx context.Context) bool {
 
 func info[T any](ctx context.Context) bool {
 	if entry := getEntry[T](ctx); entry != nil {
-		entry.level = INFO
+		entry.setLevel(INFO)
 		return true
 	}
 
@@ -248,7 +1002,7 @@ func info[T any](ctx context.Context) bool {
 
 func warn[T any](ctx context.Context) bool {
 	if entry := getEntry[T](ctx); entry != nil {
-		entry.level = WARN
+		entry.setLevel(WARN)
 		return true
 	}
 
@@ -257,7 +1011,7 @@ func warn[T any](ctx context.Context) bool {
 
 func err[T any](ctx context.Context) bool {
 	if entry := getEntry[T](ctx); entry != nil {
-		entry.level = ERROR
+		entry.setLevel(ERROR)
 		return true
 	}
 
@@ -266,7 +1020,7 @@ func err[T any](ctx context.Context) bool {
 
 func fatal[T any](ctx context.Context) bool {
 	if entry := getEntry[T](ctx); entry != nil {
-		entry.level = FATAL
+		entry.setLevel(FATAL)
 		return true
 	}
 
@@ -282,6 +1036,13 @@ func DefaultLevel(level Level) MiddlewareOption {
 	return MiddlewareOption(WithDefaultLevel(level))
 }
 
+// MessageKey sets the key under which [Msg] and [Msgf] write the message of
+// the log entries produced by the [Middleware], as described by
+// [WithMessageKey].
+func MessageKey(key string) MiddlewareOption {
+	return MiddlewareOption(WithMessageKey(key))
+}
+
 // PrintLevel sets the minimum log level for printing log entries produced by
 // the [Middleware].
 func PrintLevel(level Level) MiddlewareOption {
@@ -293,6 +1054,21 @@ func Output(out io.Writer) MiddlewareOption {
 	return MiddlewareOption(WithOutput(out))
 }
 
+// LevelOutput configures the middleware to write log entries at or above the
+// level to w, as described by [WithLevelOutput].
+func LevelOutput(level Level, w io.Writer) MiddlewareOption {
+	return MiddlewareOption(WithLevelOutput(level, w))
+}
+
+// WithTimer configures the middleware to use a custom [Timer] to measure
+// request durations and to timestamp log entries. This is useful if you need
+// to control the passage of time in tests. It is overridden by [WithTiming].
+func WithTimer(timer Timer) MiddlewareOption {
+	return func(o *option) {
+		o.timer = timer
+	}
+}
+
 // WithTiming configures the middleware to always print logs with the given
 // timestamp and the given duration.
 func WithTiming(now time.Time, since time.Duration) MiddlewareOption {
@@ -0,0 +1,128 @@
+// Package logstest provides utilities for testing applications that log using
+// the logs package.
+package logstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Recorder is an io.Writer that decodes each log entry written to it and keeps
+// it in memory. Use it with logs.WithOutput or logs.Output to inspect the log
+// entries that your application prints.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []map[string]any
+}
+
+// NewRecorder creates an empty [Recorder].
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Write decodes each line of p as a JSON object and records it. It returns an
+// error if any line is not a JSON object.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+
+		var m map[string]any
+		if err := dec.Decode(&m); err != nil {
+			return 0, fmt.Errorf("failed to decode log entry: %w", err)
+		}
+
+		r.entries = append(r.entries, m)
+	}
+
+	return len(p), nil
+}
+
+// Entries returns the recorded log entries, in the order they were written.
+func (r *Recorder) Entries() []map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]map[string]any(nil), r.entries...)
+}
+
+// Last returns the most recently recorded log entry, or nil if no log entries
+// have been recorded.
+func (r *Recorder) Last() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.entries) == 0 {
+		return nil
+	}
+
+	return r.entries[len(r.entries)-1]
+}
+
+// Reset discards the recorded log entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = nil
+}
+
+// Field finds the value at a dot-notation key within the most recently
+// recorded log entry. The function will return false if the key does not
+// exist. Numbers are provided as json.Number.
+func (r *Recorder) Field(key string) (any, bool) {
+	var current any = r.Last()
+	for _, sub := range strings.Split(key, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		if current, ok = m[sub]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// AssertField fails the test if the value at a dot-notation key within the
+// most recently recorded log entry does not equal want. Values are compared by
+// their JSON encodings, so want may be of any type that encodes to the same
+// JSON as the logged value, such as an int for a logged number.
+func (r *Recorder) AssertField(t testing.TB, key string, want any) {
+	t.Helper()
+
+	got, ok := r.Field(key)
+	if !ok {
+		t.Errorf("log entry has no %q field", key)
+		return
+	}
+
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Errorf("failed to encode %q field: %v", key, err)
+		return
+	}
+
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Errorf("failed to encode expected value of %q field: %v", key, err)
+		return
+	}
+
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("log entry field %q is %s, want %s", key, gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,73 @@
+package logstest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rclark/logs"
+	"github.com/rclark/logs/logstest"
+)
+
+func ExampleRecorder() {
+	recorder := logstest.NewRecorder()
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+	for _, name := range []string{"first", "second"} {
+		ctx := logger.AddEntry(context.Background())
+		logger.Adjust(ctx, func(e *logs.ExampleLog) {
+			e.Name = name
+			e.Count = len(name)
+		})
+		logger.Print(ctx, logs.WithOutput(recorder))
+	}
+
+	fmt.Println(len(recorder.Entries()))
+	fmt.Println(recorder.Last()["name"])
+
+	count, _ := recorder.Field("count")
+	fmt.Println(count)
+	// Output:
+	// 2
+	// second
+	// 6
+}
+
+func TestRecorder_AssertField(t *testing.T) {
+	recorder := logstest.NewRecorder()
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+	ctx := logger.AddEntry(context.Background())
+	logger.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+		e.Count = 42
+		e.Messages = []string{"hello"}
+	})
+	logger.Error(ctx)
+	logger.Print(ctx, logs.WithOutput(recorder))
+
+	recorder.AssertField(t, "@level", "ERROR")
+	recorder.AssertField(t, "name", "test")
+	recorder.AssertField(t, "count", 42)
+	recorder.AssertField(t, "messages", []string{"hello"})
+
+	for key, want := range map[string]any{"count": 43, "missing": 1} {
+		tb := &failureTB{TB: t}
+		recorder.AssertField(tb, key, want)
+		if !tb.failed {
+			t.Errorf("expected assertion on %q to fail", key)
+		}
+	}
+}
+
+// failureTB records assertion failures without failing the test.
+type failureTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *failureTB) Helper() {}
+
+func (f *failureTB) Errorf(format string, args ...any) {
+	f.failed = true
+}
@@ -0,0 +1,56 @@
+package logs
+
+import (
+	"context"
+	"reflect"
+)
+
+// MergeFunc folds the data of one log entry into another, for use with
+// [Logger.Merge]. The function is called with the log entry in the context
+// as into, and a deep copy of the other log entry as from.
+type MergeFunc[T any] func(into, from *T)
+
+// merge folds a deep copy of other into the log entry in the context, holding
+// the log entry's lock so that concurrent updates to its counters are not
+// lost.
+func merge[T any](ctx context.Context, other *T, fn MergeFunc[T]) bool {
+	entry := getMutableEntry[T](ctx)
+	if entry == nil || other == nil {
+		return false
+	}
+
+	from := deepCopy(reflect.ValueOf(other)).Interface().(*T)
+
+	entry.metrics.Lock()
+	defer entry.metrics.Unlock()
+
+	fn(entry.data, from)
+	return true
+}
+
+// mergeFields merges the fields of from into the map. Nested maps are merged
+// field by field, and any other value replaces the existing one.
+func mergeFields(into, from map[string]any) {
+	for k, v := range from {
+		src, ok := asMap(v)
+		dst, exists := asMap(into[k])
+		if ok && exists {
+			mergeFields(dst, src)
+			continue
+		}
+
+		into[k] = v
+	}
+}
+
+// asMap returns a value as a map, if it is one.
+func asMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case FreeformEntry:
+		return m, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,190 @@
+package logs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// MetaField is an additional field that is printed alongside the "@level" and
+// "@time" fields of every log entry, such as those added by [WithEnvField] and
+// [WithRunID].
+type MetaField struct {
+	Key   string
+	Value any
+}
+
+// appendMeta appends meta fields to the beginning of a JSON object. Fields that
+// cannot be marshaled to JSON are skipped.
+func appendMeta(data []byte, fields []MetaField) []byte {
+	for _, f := range fields {
+		key, err := json.Marshal(f.Key)
+		if err != nil {
+			continue
+		}
+
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			continue
+		}
+
+		data = append(data, ',')
+		data = append(data, key...)
+		data = append(data, ':')
+		data = append(data, value...)
+	}
+
+	return data
+}
+
+// envCache holds the values of environment variables that have been read by
+// [WithEnvField].
+var envCache sync.Map
+
+// lookupEnv reads an environment variable once, and returns the cached value
+// on subsequent calls.
+func lookupEnv(name string) (string, bool) {
+	type envValue struct {
+		value string
+		ok    bool
+	}
+
+	if v, ok := envCache.Load(name); ok {
+		env := v.(envValue)
+		return env.value, env.ok
+	}
+
+	value, ok := os.LookupEnv(name)
+	v, _ := envCache.LoadOrStore(name, envValue{value, ok})
+	env := v.(envValue)
+	return env.value, env.ok
+}
+
+// WithEnvField configures printing to include the value of an environment
+// variable as a meta field of every log entry, alongside the "@level" and
+// "@time" fields. The environment variable is read once and cached for the
+// life of the process. If the environment variable is not set, the field is
+// omitted.
+//
+// If envVar is empty, the "ENV" environment variable is read. If fieldName is
+// empty, the value is written to the "@env" field.
+func WithEnvField(envVar, fieldName string) PrintOption {
+	if envVar == "" {
+		envVar = "ENV"
+	}
+
+	if fieldName == "" {
+		fieldName = "@env"
+	}
+
+	return func(o *option) {
+		if value, ok := lookupEnv(envVar); ok {
+			o.meta = append(o.meta, MetaField{fieldName, value})
+		}
+	}
+}
+
+// runID holds the ID that identifies the current execution of the process.
+var runID = struct {
+	sync.Mutex
+	generate func() string
+	id       string
+}{generate: randomID}
+
+// randomID generates a random 128-bit identifier encoded as hexadecimal.
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// currentRunID returns the ID of the current execution of the process,
+// generating it the first time it is needed.
+func currentRunID() string {
+	runID.Lock()
+	defer runID.Unlock()
+
+	if runID.id == "" {
+		runID.id = runID.generate()
+	}
+
+	return runID.id
+}
+
+// SetRunIDGenerator overrides the function used to generate the run ID printed
+// by [WithRunID], and discards any run ID that has already been generated. This
+// is useful to produce a deterministic run ID in tests. Passing nil restores
+// the default generator, which produces random run IDs.
+func SetRunIDGenerator(generate func() string) {
+	runID.Lock()
+	defer runID.Unlock()
+
+	if generate == nil {
+		generate = randomID
+	}
+
+	runID.generate = generate
+	runID.id = ""
+}
+
+// WithRunID configures printing to include a run ID as the "@run_id" meta
+// field of every log entry. A single random run ID is generated for the life
+// of the process, so all of the logs from one execution of an application,
+// such as a batch job that fans out work, can be grouped together.
+func WithRunID() PrintOption {
+	return func(o *option) {
+		o.meta = append(o.meta, MetaField{"@run_id", currentRunID()})
+	}
+}
+
+// processInfo holds the hostname and process ID, which are looked up once for
+// the life of the process.
+var processInfo = sync.OnceValues(func() (string, int) {
+	hostname, _ := os.Hostname()
+	return hostname, os.Getpid()
+})
+
+// WithProcessInfo configures printing to include the name of the host as the
+// "@host" meta field, and the process ID as the "@pid" meta field, of every
+// log entry. Both are looked up once and cached for the life of the process.
+// If the hostname cannot be found, the "@host" field is omitted.
+func WithProcessInfo() PrintOption {
+	return func(o *option) {
+		hostname, pid := processInfo()
+		if hostname != "" {
+			o.meta = append(o.meta, MetaField{"@host", hostname})
+		}
+		o.meta = append(o.meta, MetaField{"@pid", pid})
+	}
+}
+
+// ProcessInfo configures the middleware to include the hostname and process
+// ID in every log entry, as described by [WithProcessInfo].
+func ProcessInfo() MiddlewareOption {
+	return MiddlewareOption(WithProcessInfo())
+}
+
+// WithService configures printing to include the identity of the service as
+// meta fields of every log entry: its name as "@service", its version as
+// "@version", and the environment it is deployed to as "@env". Empty values
+// are omitted.
+func WithService(name, version, env string) PrintOption {
+	return func(o *option) {
+		for _, f := range []MetaField{{"@service", name}, {"@version", version}, {"@env", env}} {
+			if f.Value != "" {
+				o.meta = append(o.meta, f)
+			}
+		}
+	}
+}
+
+// Service configures the middleware to include the identity of the service in
+// every log entry, as described by [WithService].
+func Service(name, version, env string) MiddlewareOption {
+	return MiddlewareOption(WithService(name, version, env))
+}
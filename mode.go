@@ -0,0 +1,41 @@
+package logs
+
+import "context"
+
+// FreeformMode returns the [Logger] for freeform log entries, which are
+// [FreeformEntry] maps that you build up by adding key-value pairs. The
+// package-level functions like [Print] and [Middleware] are equivalent to its
+// methods, and functions like [Add] and [Count] work with the freeform log
+// entries that it creates.
+func FreeformMode() Logger[FreeformEntry] {
+	return For[FreeformEntry]()
+}
+
+// For returns a [Logger] for log entries of type T, which gives you functions
+// similar to the freeform method but using your custom type. The logger creates
+// log entries using the [Logger] for T that was placed in the context using
+// [Logger.Set], or creates empty log entries if there is none. The freeform and
+// structured APIs can be used together in one application.
+func For[T any]() Logger[T] {
+	return Logger[T]{}
+}
+
+// AppendTo appends values to a slice field of the structured log entry in the
+// context. The selector function is given the log entry and must return a
+// pointer to the field, which lets you append to a field without writing an
+// [Adjuster] for it:
+//
+//	logs.AppendTo(ctx, func(e *MyLog) *[]string { return &e.Messages }, "more")
+//
+// The function will return false if no log entry of type T is found in the
+// context.
+func AppendTo[T, V any](ctx context.Context, selector func(*T) *[]V, values ...V) bool {
+	if entry := getMutableEntry[T](ctx); entry != nil {
+		if field := selector(entry.data); field != nil {
+			*field = append(*field, values...)
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+// Package otellog connects the logs package to OpenTelemetry tracing, so that
+// log entries can be correlated with the traces of the work that printed them.
+package otellog
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rclark/logs"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTrace configures printing to add the "trace_id", "span_id", and
+// "trace_flags" fields of the OpenTelemetry span in the context to the log
+// entry. The fields are omitted if there is no valid span context in the
+// context.
+func WithTrace() logs.PrintOption {
+	return logs.WithContextFields(fields)
+}
+
+// Trace configures the logs package's middleware to extract the W3C trace
+// context from the "traceparent" and "tracestate" headers of each request, and
+// to add the trace fields to each log entry as described by [WithTrace]. The
+// trace context is added to the request's context as a remote span context, so
+// spans started by the downstream handler become its children.
+func Trace() logs.MiddlewareOption {
+	return join(logs.WithRequestContext(extract), logs.ContextFields(fields))
+}
+
+// extract reads the W3C trace context from the request's headers into its
+// context.
+func extract(r *http.Request) context.Context {
+	return propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+}
+
+// fields provides the meta fields that identify the span in the context.
+func fields(ctx context.Context) []logs.MetaField {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []logs.MetaField{
+		{Key: "trace_id", Value: sc.TraceID().String()},
+		{Key: "span_id", Value: sc.SpanID().String()},
+		{Key: "trace_flags", Value: sc.TraceFlags().String()},
+	}
+}
+
+// join combines options into one that applies each of them in turn.
+func join[O ~func(P), P any](opts ...O) O {
+	return func(p P) {
+		for _, opt := range opts {
+			opt(p)
+		}
+	}
+}
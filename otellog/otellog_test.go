@@ -0,0 +1,48 @@
+package otellog_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/rclark/logs"
+	"github.com/rclark/logs/otellog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func ExampleWithTrace() {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	logger := logs.NewLogger(logs.NewExampleLog)
+	ctx = logger.AddEntry(ctx)
+
+	logger.Print(ctx, logs.WithCurrentTime(time.Time{}), otellog.WithTrace())
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01","name":"","count":0,"flag":false}
+}
+
+func ExampleTrace() {
+	middleware := logs.NewLogger(logs.NewExampleLog).Middleware(
+		logs.WithTiming(time.Time{}, time.Duration(1234)),
+		otellog.Trace(),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/path", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc := trace.SpanContextFromContext(r.Context())
+		fmt.Println(sc.TraceID(), sc.IsRemote())
+	})).ServeHTTP(w, r)
+	// Output:
+	// 4bf92f3577b34da6a3ce929d0e0e4736 true
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01","name":"","count":0,"flag":false}
+}
@@ -0,0 +1,85 @@
+package logs
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// WithPooling configures the log entry to be taken from a pool when it is
+// created, to reduce allocations in high-throughput applications. The maps of
+// freeform log entries are cleared and reused; log entries of custom types are
+// created by the [EntryMaker] as usual.
+//
+// A pooled log entry is returned to the pool by the code that created it, once
+// that code has finished with it: the [Middleware] and [Logger.Middleware] once
+// the handler has returned and the log entry has been printed, and likewise
+// [Job] and [ConsumeMiddleware]. Printing the log entry in other ways does not
+// return it to the pool, and a log entry that you add to a context yourself
+// using [AddEntry] is never returned. A pooled log entry must not be used in
+// any way after it has been returned, including through the context that held
+// it, as it may already belong to another request.
+func WithPooling() Option {
+	return func(o *option) {
+		o.pooling = true
+	}
+}
+
+// Pooling configures the middleware to take log entries from a pool, as
+// described by [WithPooling]. The middleware prints each log entry once the
+// handler has returned, so handlers must not retain the request's context.
+func Pooling() MiddlewareOption {
+	return MiddlewareOption(WithPooling())
+}
+
+// entryPools holds a pool of log entries for each log entry type.
+var entryPools sync.Map
+
+func entryPool[T any]() *sync.Pool {
+	key := reflect.TypeFor[T]()
+	if pool, ok := entryPools.Load(key); ok {
+		return pool.(*sync.Pool)
+	}
+
+	pool, _ := entryPools.LoadOrStore(key, &sync.Pool{
+		New: func() any { return new(entry[T]) },
+	})
+	return pool.(*sync.Pool)
+}
+
+// acquireEntry takes a log entry from the pool.
+func acquireEntry[T any](create EntryMaker[T]) *entry[T] {
+	e := entryPool[T]().Get().(*entry[T])
+	e.pooled = true
+	if e.data == nil {
+		e.data = create()
+	}
+
+	return e
+}
+
+// releaseEntry returns the pooled log entry in the context to the pool. It is
+// only called by the code that created the log entry, once that code has
+// finished with it.
+func releaseEntry[T any](ctx context.Context) {
+	if e := getEntry[T](ctx); e != nil {
+		e.release()
+	}
+}
+
+// release returns a pooled log entry to the pool, keeping the map of a
+// freeform log entry for reuse.
+func (e *entry[T]) release() {
+	if !e.pooled {
+		return
+	}
+
+	data := e.data
+	*e = entry[T]{}
+	if m, ok := any(data).(*FreeformEntry); ok {
+		clear(*m)
+		e.data = data
+	}
+
+	entryPool[T]().Put(e)
+}
@@ -0,0 +1,233 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxRateLimitKeys is the number of keys whose buckets a rate limiter tracks
+// before it forgets the buckets that are full.
+const maxRateLimitKeys = 1024
+
+// RateLimitOption is a configuration option for [NewRateLimiter].
+type RateLimitOption func(*RateLimiter)
+
+// RateLimitByField gives each value of a field of the log entry, such as
+// "@http.route" or "error.type", its own rate limit. The key uses the same dot
+// notation as [Add], and is looked up in the log entry's JSON for custom log
+// entry types. Log entries without the field share a rate limit.
+func RateLimitByField(key string) RateLimitOption {
+	return RateLimitBy(func(e any) string {
+		m, ok := e.(*FreeformEntry)
+		if !ok {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return ""
+			}
+			fields, ok := toMap(data)
+			if !ok {
+				return ""
+			}
+			m = (*FreeformEntry)(&fields)
+		}
+
+		if v, ok := lookupPath(*m, key); ok {
+			return fmt.Sprint(v)
+		}
+		return ""
+	})
+}
+
+// RateLimitBy gives each key returned by fn its own rate limit. The log entry
+// is provided as a pointer to its type, such as *[FreeformEntry].
+func RateLimitBy(fn func(entry any) string) RateLimitOption {
+	return func(l *RateLimiter) {
+		l.key = fn
+	}
+}
+
+// RateLimitSummary sets how often a summary of the dropped log entries is
+// printed. The default is one minute.
+func RateLimitSummary(every time.Duration) RateLimitOption {
+	return func(l *RateLimiter) {
+		l.every = every
+	}
+}
+
+// RateLimiter caps the number of log entries that are printed, so that a burst
+// of log entries, such as a storm of identical errors, does not overwhelm the
+// output. Use [WithRateLimit] or [RateLimit] to apply it when printing. It is
+// safe for concurrent use.
+type RateLimiter struct {
+	capacity float64
+	rate     float64
+	every    time.Duration
+	key      func(any) string
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	dropped     map[string]int
+	total       int
+	lastSummary time.Time
+	opts        option
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a [RateLimiter] that allows no more than n log entries
+// per period. Log entries are allowed using a token bucket that holds n tokens
+// and refills at a rate of n per period, so short bursts of up to n log entries
+// are emitted in full. Log entries that exceed the limit are dropped, unless
+// they were marked using [Force].
+//
+// While log entries are being dropped, a summary is printed at WARN level,
+// once per summary interval, under the "@rate_limit" key. It reports the
+// number of log entries dropped since the previous summary, in total and for
+// each key if [RateLimitByField] or [RateLimitBy] is used. The summary is
+// printed when a later log entry is checked against the limit, so call
+// [RateLimiter.Flush] before the application exits to print the summary of
+// any log entries dropped since then.
+func NewRateLimiter(n int, per time.Duration, opts ...RateLimitOption) *RateLimiter {
+	l := &RateLimiter{
+		capacity: float64(n),
+		rate:     float64(n) / per.Seconds(),
+		every:    time.Minute,
+		buckets:  make(map[string]*bucket),
+		dropped:  make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// WithRateLimit configures printing to drop log entries that exceed the limit
+// of the [RateLimiter]. The limit is shared by every log entry printed using
+// the rate limiter.
+func WithRateLimit(l *RateLimiter) PrintOption {
+	return func(o *option) {
+		o.rateLimit = l
+	}
+}
+
+// RateLimit configures the middleware to drop log entries that exceed the limit
+// of the [RateLimiter], as described by [WithRateLimit].
+func RateLimit(l *RateLimiter) MiddlewareOption {
+	return MiddlewareOption(WithRateLimit(l))
+}
+
+// Flush prints a summary of the log entries dropped since the previous summary,
+// if there are any, without waiting for the summary interval to pass. The
+// summary is printed using the print options of the last dropped log entry.
+func (l *RateLimiter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.total == 0 {
+		return nil
+	}
+
+	return l.report(l.opts.timer.Now(), true)
+}
+
+// allow reports whether a log entry may be printed, printing the summary of
+// dropped log entries if one is due.
+func (l *RateLimiter) allow(entry any, o option) (bool, error) {
+	key := ""
+	if l.key != nil {
+		key = l.key(entry)
+	}
+
+	now := o.timer.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lastSummary.IsZero() {
+		l.lastSummary = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitKeys {
+			l.forget(now)
+		}
+		b = &bucket{tokens: l.capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(l.capacity, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	} else {
+		l.total++
+		l.dropped[key]++
+		l.opts = o
+		l.opts.rateLimit = nil
+	}
+
+	return allowed, l.report(now, false)
+}
+
+// forget removes the buckets that have refilled, which behave the same as new
+// ones.
+func (l *RateLimiter) forget(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// report prints a log entry reporting the log entries dropped since the
+// previous summary, if any were dropped and a summary is due or forced. The
+// counts are kept if the summary cannot be printed, so that they are reported
+// by the next summary.
+func (l *RateLimiter) report(now time.Time, force bool) error {
+	if l.total == 0 || (!force && now.Sub(l.lastSummary) < l.every) {
+		return nil
+	}
+
+	report := map[string]any{"dropped": l.total}
+	if l.key != nil {
+		keys := make(map[string]any, len(l.dropped))
+		for k, n := range l.dropped {
+			keys[k] = n
+		}
+		report["keys"] = keys
+	}
+
+	summary := FreeformEntry{"@rate_limit": report}
+	e := &entry[FreeformEntry]{level: WARN, timer: l.opts.timer, data: &summary}
+	if err := emit(context.Background(), e, WARN, l.opts); err != nil {
+		return fmt.Errorf("failed to print rate limit summary: %w", err)
+	}
+
+	l.total = 0
+	clear(l.dropped)
+	l.lastSummary = now
+	return nil
+}
+
+// rateLimited reports whether a log entry should be dropped by the rate limit,
+// printing a summary of dropped log entries if one is due.
+func (o option) rateLimited(data any) (bool, error) {
+	if o.rateLimit == nil {
+		return false, nil
+	}
+
+	allowed, err := o.rateLimit.allow(data, o)
+	return !allowed, err
+}
@@ -0,0 +1,159 @@
+package logs
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Redacted is the value that replaces redacted fields in printed log entries.
+const Redacted = "[REDACTED]"
+
+// WithRedaction configures printing to replace the values of the specified
+// keys with [Redacted]. Keys are matched case-insensitively, either against a
+// field's full dot-notation key or against its name at any depth, so
+// "password" redacts both "password" and "user.password". The log entry itself
+// is not modified.
+//
+// Fields of a custom log entry type that are tagged `logs:"redact"` are always
+// redacted, whether or not this option is used.
+func WithRedaction(keys ...string) PrintOption {
+	return func(o *option) {
+		for _, key := range keys {
+			o.redactKeys = append(o.redactKeys, strings.ToLower(key))
+		}
+	}
+}
+
+// WithRedactor configures printing to pass every field of the log entry to fn,
+// along with its dot-notation key. If fn returns true, the field's value is
+// replaced with the value that fn returned. Values are provided as they were
+// decoded from the log entry's JSON, so nested objects are maps and numbers
+// are json.Number. The log entry itself is not modified.
+func WithRedactor(fn func(key string, value any) (any, bool)) PrintOption {
+	return func(o *option) {
+		o.redactor = fn
+	}
+}
+
+// redacts reports whether printing will redact any fields of the log entry.
+func (o option) redacts(v any) bool {
+	return o.redactKeys != nil || o.redactor != nil || len(taggedFields(v)) > 0
+}
+
+// redact replaces the values of redacted fields within the map.
+func redact(m map[string]any, prefix string, tagged []string, o option) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if o.redactsKey(key, k, tagged) {
+			m[k] = Redacted
+			continue
+		}
+
+		if o.redactor != nil {
+			if replaced, ok := o.redactor(key, v); ok {
+				m[k] = replaced
+				continue
+			}
+		}
+
+		if nested, ok := v.(map[string]any); ok {
+			redact(nested, key, tagged, o)
+		}
+	}
+}
+
+// redactsKey reports whether the field with the dot-notation key and name
+// should be redacted.
+func (o option) redactsKey(key, name string, tagged []string) bool {
+	for _, t := range tagged {
+		if t == key {
+			return true
+		}
+	}
+
+	key, name = strings.ToLower(key), strings.ToLower(name)
+	for _, r := range o.redactKeys {
+		if r == key || r == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// taggedCache holds the dot-notation keys of the fields tagged for redaction
+// for each custom log entry type.
+var taggedCache sync.Map
+
+// taggedFields uses reflection to find the dot-notation JSON keys of a
+// struct's fields that are tagged `logs:"redact"`. Fields of embedded and
+// nested structs are included. The result is cached for each type.
+func taggedFields(v any) []string {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if keys, ok := taggedCache.Load(rt); ok {
+		return keys.([]string)
+	}
+
+	keys := appendTagged(nil, rt, "", map[reflect.Type]bool{})
+	taggedCache.Store(rt, keys)
+	return keys
+}
+
+func appendTagged(keys []string, rt reflect.Type, prefix string, seen map[reflect.Type]bool) []string {
+	if seen[rt] {
+		return keys
+	}
+	seen[rt] = true
+	defer delete(seen, rt)
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			keys = appendTagged(keys, ft, prefix, seen)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		if field.Tag.Get("logs") == "redact" {
+			keys = append(keys, name)
+		} else if ft.Kind() == reflect.Struct {
+			keys = appendTagged(keys, ft, name, seen)
+		}
+	}
+
+	return keys
+}
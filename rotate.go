@@ -0,0 +1,300 @@
+package logs
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the layout of the timestamp in the names of rotated log
+// files. It sorts chronologically and contains no characters that are invalid
+// in file names.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFileWriter is an io.Writer that writes to a file, and rotates the
+// file once it reaches a maximum size. Use it with [WithOutput] to print log
+// entries to disk. It is safe for concurrent use.
+//
+// A rotated file is renamed to include the time of its rotation, such as
+// "app-2024-01-02T15-04-05.000.log" for "app.log", and a new file is created
+// in its place. Rotated files may be compressed using gzip, and are removed
+// once there are too many of them or they are too old.
+//
+// Files are rotated only when they reach the maximum size, or when
+// [RotatingFileWriter.Rotate] is called. To rotate on a schedule, such as
+// daily, call Rotate from a time.Ticker.
+type RotatingFileWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+
+	// cleanup serializes the removal and compression of rotated files, which
+	// happens in the background.
+	cleanup sync.Mutex
+	pending sync.WaitGroup
+}
+
+// NewRotatingFileWriter creates a [RotatingFileWriter] that writes to the file
+// at path, creating it and its directory if necessary. The file is rotated
+// before a write would grow it beyond maxSizeMB megabytes. At most maxBackups
+// rotated files are kept, and rotated files older than maxAgeDays days are
+// removed. If compress is true, rotated files are compressed using gzip. A
+// value of zero for maxSizeMB, maxBackups or maxAgeDays means no limit.
+func NewRotatingFileWriter(path string, maxSizeMB, maxBackups, maxAgeDays int, compress bool) (*RotatingFileWriter, error) {
+	w := &RotatingFileWriter{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+		maxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+		compress:   compress,
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write writes data to the file, rotating it first if the write would grow
+// the file beyond its maximum size. Data is never split across files. If the
+// file cannot be rotated, the error is written to os.Stderr and data is
+// written to the current file.
+func (w *RotatingFileWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.reopen(); err != nil {
+		return 0, err
+	}
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(data)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to rotate log file: %v\n", err)
+			if w.file == nil {
+				return 0, err
+			}
+		}
+	}
+
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file immediately, such as in response to a signal from an
+// external tool that manages log files.
+func (w *RotatingFileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.reopen(); err != nil {
+		return err
+	}
+
+	return w.rotate()
+}
+
+// Close closes the file, and waits for the removal and compression of rotated
+// files to complete. Writes after Close return [ErrWriterClosed].
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.closed = true
+
+	w.pending.Wait()
+	return err
+}
+
+// open opens the file for appending, creating it if necessary.
+func (w *RotatingFileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file, w.size = file, info.Size()
+	return nil
+}
+
+// reopen opens the file if a failed rotation left it closed. It returns
+// [ErrWriterClosed] if the writer has been closed.
+func (w *RotatingFileWriter) reopen() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	if w.file == nil {
+		return w.open()
+	}
+
+	return nil
+}
+
+// rotate renames the current file with a timestamp, opens a new one, and
+// starts removing and compressing rotated files in the background. If the file
+// cannot be renamed, the original file is opened again. If no file can be
+// opened, later writes try again.
+func (w *RotatingFileWriter) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return errors.Join(err, w.open())
+	}
+
+	ext := filepath.Ext(w.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(w.path, ext), time.Now().UTC().Format(backupTimeFormat), ext)
+	if err := os.Rename(w.path, backup); err != nil && !os.IsNotExist(err) {
+		return errors.Join(err, w.open())
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.pending.Add(1)
+	go func() {
+		defer w.pending.Done()
+		w.cleanup.Lock()
+		defer w.cleanup.Unlock()
+
+		if err := w.clean(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to clean up rotated log files: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// rotated is a rotated log file.
+type rotated struct {
+	path string
+	time time.Time
+}
+
+// backups lists the rotated log files, newest first.
+func (w *RotatingFileWriter) backups() ([]rotated, error) {
+	dir := filepath.Dir(w.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+
+	var files []rotated
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimPrefix(strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext), prefix)
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, rotated{filepath.Join(dir, name), t})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].time.After(files[j].time)
+	})
+
+	return files, nil
+}
+
+// clean removes rotated files beyond the maximum number or age, and compresses
+// those that remain.
+func (w *RotatingFileWriter) clean() error {
+	files, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-w.maxAge)
+	for i, f := range files {
+		if (w.maxBackups > 0 && i >= w.maxBackups) || (w.maxAge > 0 && f.time.Before(cutoff)) {
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+
+		if w.compress && !strings.HasSuffix(f.path, ".gz") {
+			if err := compressFile(f.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// compressFile compresses a file using gzip, replacing it with a file of the
+// same name with a ".gz" suffix.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,76 @@
+package logs
+
+import (
+	"errors"
+	"io"
+)
+
+// Sink is a destination for log entries that a [Router] writes to.
+type Sink struct {
+	// Writer is where log entries are written.
+	Writer io.Writer
+	// Encoder encodes log entries for the sink. If nil, the encoder used for
+	// printing is used, which is a [JSONEncoder] unless changed using
+	// [WithEncoder].
+	Encoder Encoder
+	// Level is the minimum level of the log entries that are written to the
+	// sink.
+	Level Level
+}
+
+// Router writes each log entry to multiple sinks, each with its own encoder and
+// minimum level. For example, a router could write JSON to os.Stdout at INFO,
+// a [ConsoleEncoder] view to a developer's terminal at DEBUG, and ERROR log
+// entries to a file. Use it as the output for printing with [WithOutput] or
+// [Output].
+//
+// A log entry is only offered to the router if it passes the level for
+// printing set by [WithLevel], so that level should be no higher than the
+// lowest level of the router's sinks.
+type Router struct {
+	sinks []Sink
+}
+
+// NewRouter creates a [Router] that writes to the sinks.
+func NewRouter(sinks ...Sink) *Router {
+	return &Router{sinks: append([]Sink{}, sinks...)}
+}
+
+// Write copies data to every sink as-is. It is used when the router receives
+// data that is not a log entry being printed, such as when it is wrapped by an
+// [AsyncWriter], in which case the sinks' encoders and levels do not apply.
+func (r *Router) Write(data []byte) (int, error) {
+	var errs []error
+	for _, sink := range r.sinks {
+		if _, err := sink.Writer.Write(data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return len(data), errors.Join(errs...)
+}
+
+// route encodes and writes a log entry to each sink whose level it meets.
+func (r *Router) route(data []byte, meta Metadata, o option) error {
+	var errs []error
+	for _, sink := range r.sinks {
+		if meta.Level < sink.Level {
+			continue
+		}
+
+		so := o
+		if sink.Encoder != nil {
+			so.encoder = sink.Encoder
+		}
+
+		line, err := fitLine(data, meta, so)
+		if err == nil {
+			_, err = writeMeta(sink.Writer, meta, line)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
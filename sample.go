@@ -0,0 +1,88 @@
+package logs
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// WithSampling configures printing to emit only a fraction of the log entries
+// below WARN level, chosen at random. A rate of 0.1 emits about one in ten of
+// those log entries. Log entries at WARN level or above are always emitted.
+func WithSampling(rate float64) PrintOption {
+	return WithSampler(sampleRate(rate))
+}
+
+// WithSampler configures printing to call fn for each log entry below WARN
+// level to decide whether to emit it. The log entry is provided as a pointer to
+// its type, such as *[FreeformEntry]. Log entries at WARN level or above are
+// always emitted.
+func WithSampler(fn func(ctx context.Context, entry any) bool) PrintOption {
+	return func(o *option) {
+		o.sampler = fn
+	}
+}
+
+// Sampling configures the middleware to emit only a fraction of the log
+// entries below WARN level, as described by [WithSampling].
+func Sampling(rate float64) MiddlewareOption {
+	return MiddlewareOption(WithSampling(rate))
+}
+
+// Sampler configures the middleware to call fn for each log entry below WARN
+// level to decide whether to emit it, as described by [WithSampler].
+func Sampler(fn func(ctx context.Context, entry any) bool) MiddlewareOption {
+	return MiddlewareOption(WithSampler(fn))
+}
+
+func sampleRate(rate float64) func(context.Context, any) bool {
+	return func(context.Context, any) bool {
+		return rand.Float64() < rate
+	}
+}
+
+// sampled reports whether a log entry at the given level should be emitted.
+func (o option) sampled(ctx context.Context, entry any, level Level) bool {
+	return o.sampler == nil || level >= WARN || o.sampler(ctx, entry)
+}
+
+// WithEmitWhen configures the middleware to print a request's log entry only if
+// fn returns true, once the handler has returned. It is called with the
+// response status, the duration of the request, and the log entry as a pointer
+// to its type, such as *[FreeformEntry]. This lets routine requests be skipped
+// while the full log entry is kept for the ones that matter. A log entry that
+// the handler marked using [Force] is always printed.
+func WithEmitWhen(fn func(status int, duration time.Duration, entry any) bool) MiddlewareOption {
+	return func(o *option) {
+		o.emitWhen = fn
+	}
+}
+
+// EmitOnErrorOrSlow configures the middleware to print a request's log entry
+// only if the response status is 500 or above, the log entry's level is ERROR
+// or above, or the request took at least the threshold. It is a preset of
+// [WithEmitWhen].
+func EmitOnErrorOrSlow(threshold time.Duration) MiddlewareOption {
+	return func(o *option) {
+		o.emitWhen = func(status int, duration time.Duration, entry any) bool {
+			return status >= http.StatusInternalServerError || duration >= threshold
+		}
+		o.emitErrors = true
+	}
+}
+
+// emits reports whether the middleware should print the log entry in the
+// context, given the response status and the duration of the request.
+func emits[T any](ctx context.Context, status int, duration time.Duration, o option) bool {
+	entry := getEntry[T](ctx)
+	if o.emitWhen == nil || entry == nil || entry.forced {
+		return true
+	}
+
+	if o.emitErrors && entry.currentLevel() >= ERROR {
+		return true
+	}
+
+	return o.emitWhen(status, duration, entry.data)
+}
@@ -0,0 +1,410 @@
+// Package loki provides an output for the logs package that pushes log entries
+// to Grafana Loki.
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclark/logs"
+)
+
+// Sink is an io.Writer that collects log entries into batches and pushes them
+// to Loki's HTTP API from a background goroutine. Use it with logs.WithOutput
+// or logs.Output. It is safe for concurrent use.
+//
+// Each log entry is pushed to a stream identified by the sink's labels, and a
+// "level" label holding the log entry's level in lower case. The level is
+// passed to the sink by the logs package when printing, since the sink is a
+// logs.LevelWriter; data written using [Sink.Write] is read as JSON and takes
+// its level from an "@level" field, if there is one. Log entries are
+// timestamped when they are written to the sink.
+type Sink struct {
+	url     string
+	options options
+
+	mu      sync.Mutex
+	batch   []line
+	closed  bool
+	dropped atomic.Uint64
+	full    chan struct{}
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	errMu sync.Mutex
+	err   error
+}
+
+// line is a log entry waiting to be pushed.
+type line struct {
+	time  time.Time
+	level string
+	text  string
+}
+
+type options struct {
+	client    *http.Client
+	labels    map[string]string
+	interval  time.Duration
+	batchSize int
+	buffered  int
+	attempts  int
+	backoff   time.Duration
+	tenant    string
+}
+
+// Option is a configuration option for a [Sink].
+type Option func(*options)
+
+// WithLabels adds labels to every stream that the sink pushes to. Loki indexes
+// log entries by their labels, so they should have few distinct values.
+func WithLabels(labels map[string]string) Option {
+	return func(o *options) {
+		for k, v := range labels {
+			o.labels[k] = v
+		}
+	}
+}
+
+// WithService sets the "service" label of every stream that the sink pushes
+// to.
+func WithService(name string) Option {
+	return WithLabels(map[string]string{"service": name})
+}
+
+// WithFlushInterval sets how often the sink pushes the log entries it has
+// collected. The default, which is also kept if d is not positive, is one
+// second.
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// WithBatchSize sets the number of log entries that the sink collects before
+// pushing them, without waiting for the flush interval. The default is 1000.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithBufferSize sets the number of log entries that the sink holds while it
+// waits to push them, such as while a push is being retried. Once the buffer
+// is full, further log entries are dropped and counted by [Sink.Dropped]. The
+// default is 10000.
+func WithBufferSize(n int) Option {
+	return func(o *options) {
+		o.buffered = n
+	}
+}
+
+// WithRetry sets how many times the sink attempts to push a batch, and how
+// long it waits before the first retry. The wait doubles after each retry. A
+// batch is retried if the request fails, or if Loki responds with a 429 or 5xx
+// status. The default is 3 attempts with a backoff of 500ms.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// WithTenant sets the tenant that log entries are pushed to, using the
+// X-Scope-OrgID header, for a Loki installation with multi-tenancy enabled.
+func WithTenant(id string) Option {
+	return func(o *options) {
+		o.tenant = id
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to push log entries. The default is
+// an http.Client with a timeout of 10 seconds.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// NewSink creates a [Sink] that pushes log entries to the Loki push endpoint
+// at url, such as "http://localhost:3100/loki/api/v1/push". Call
+// [Sink.Close] to push any remaining log entries and stop the background
+// goroutine.
+func NewSink(url string, opts ...Option) *Sink {
+	o := options{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		labels:    map[string]string{},
+		interval:  time.Second,
+		batchSize: 1000,
+		buffered:  10000,
+		attempts:  3,
+		backoff:   500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Sink{
+		url:     url,
+		options: o,
+		full:    make(chan struct{}, 1),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go s.run()
+	return s
+}
+
+// Write adds each line of p to the batch waiting to be pushed, reading the
+// level of each from its "@level" field. It returns logs.ErrWriterClosed if the
+// sink has been closed. A line that is dropped because the buffer is full is
+// not reported as an error, but is counted by [Sink.Dropped].
+func (s *Sink) Write(p []byte) (int, error) {
+	return s.add(p, levelOf)
+}
+
+// WriteLevel adds each line of p to the batch waiting to be pushed, as
+// [Sink.Write] does, with the given level.
+func (s *Sink) WriteLevel(level logs.Level, p []byte) (int, error) {
+	name := strings.ToLower(level.String())
+	return s.add(p, func([]byte) string { return name })
+}
+
+// Dropped returns the number of log entries that were dropped because the
+// buffer was full.
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Sink) add(p []byte, levelOf func([]byte) string) (int, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, logs.ErrWriterClosed
+	}
+
+	for _, text := range bytes.Split(p, []byte("\n")) {
+		if len(bytes.TrimSpace(text)) == 0 {
+			continue
+		}
+
+		if len(s.batch) >= s.options.buffered {
+			s.dropped.Add(1)
+			continue
+		}
+
+		s.batch = append(s.batch, line{time: now, level: levelOf(text), text: string(text)})
+	}
+
+	if len(s.batch) >= s.options.batchSize {
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush pushes the log entries written before the call. It returns the first
+// error encountered while pushing since the previous call to Flush.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+
+	if !closed {
+		flushed := make(chan struct{})
+		select {
+		case s.flushes <- flushed:
+			<-flushed
+		case <-s.done:
+		}
+	}
+
+	return s.takeErr()
+}
+
+// Close stops accepting writes, pushes any remaining log entries, and stops the
+// background goroutine. It returns the first error encountered while pushing
+// since the last call to Flush.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+	return s.takeErr()
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.options.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.push()
+		case <-s.full:
+			s.push()
+		case flushed := <-s.flushes:
+			s.push()
+			close(flushed)
+		case <-s.stop:
+			s.push()
+			return
+		}
+	}
+}
+
+// push sends the collected log entries to Loki, retrying as configured.
+func (s *Sink) push() {
+	s.mu.Lock()
+	batch := s.batch
+	s.batch = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := encode(batch, s.options.labels)
+	if err != nil {
+		s.setErr(err)
+		return
+	}
+
+	backoff := s.options.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.send(body)
+		if err == nil {
+			return
+		}
+
+		if !retry || attempt >= s.options.attempts {
+			s.setErr(fmt.Errorf("failed to push %d log entries to loki: %w", len(batch), err))
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// send makes a single push request. It reports whether a failed request should
+// be retried.
+func (s *Sink) send(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if s.options.tenant != "" {
+		req.Header.Set("X-Scope-OrgID", s.options.tenant)
+	}
+
+	resp, err := s.options.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+func (s *Sink) setErr(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *Sink) takeErr() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// stream is a Loki stream in the body of a push request.
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encode builds the body of a push request, with a stream for each level in
+// the order that the levels first appear in the batch.
+func encode(batch []line, labels map[string]string) ([]byte, error) {
+	var streams []*stream
+	byLevel := map[string]*stream{}
+
+	for _, l := range batch {
+		st, ok := byLevel[l.level]
+		if !ok {
+			st = &stream{Stream: map[string]string{}}
+			for k, v := range labels {
+				st.Stream[k] = v
+			}
+			if l.level != "" {
+				st.Stream["level"] = l.level
+			}
+
+			byLevel[l.level] = st
+			streams = append(streams, st)
+		}
+
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(l.time.UnixNano(), 10), l.text})
+	}
+
+	data, err := json.Marshal(map[string][]*stream{"streams": streams})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode loki push request: %w", err)
+	}
+
+	return data, nil
+}
+
+// levelOf reads the level of a log entry from its "@level" field.
+func levelOf(text []byte) string {
+	var fields struct {
+		Level string `json:"@level"`
+	}
+	if err := json.Unmarshal(text, &fields); err != nil {
+		return ""
+	}
+
+	return strings.ToLower(fields.Level)
+}
@@ -0,0 +1,164 @@
+package loki_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclark/logs"
+	"github.com/rclark/logs/sinks/loki"
+)
+
+func ExampleNewSink() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Streams []struct {
+				Stream map[string]string `json:"stream"`
+				Values [][2]string       `json:"values"`
+			} `json:"streams"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, s := range body.Streams {
+			fmt.Println(s.Stream["service"], s.Stream["level"])
+			for _, v := range s.Values {
+				fmt.Println(v[1])
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := loki.NewSink(server.URL+"/loki/api/v1/push", loki.WithService("api"))
+
+	for _, path := range []string{"/users", "/orders"} {
+		ctx := logs.AddEntry(context.Background())
+		logs.Add(ctx, "path", path)
+		if path == "/orders" {
+			logs.Error(ctx)
+		}
+		logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOutput(sink))
+	}
+
+	if err := sink.Close(); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// api info
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","path":"/users"}
+	// api error
+	// {"@level":"ERROR","@time":"0001-01-01T00:00:00Z","path":"/orders"}
+}
+
+func TestSink_retry(t *testing.T) {
+	var requests atomic.Int32
+	var reject atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reject.Load() {
+			requests.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := loki.NewSink(server.URL, loki.WithRetry(3, time.Millisecond))
+	defer sink.Close()
+
+	sink.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("expected the push to succeed on the third attempt, got %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	reject.Store(true)
+	requests.Store(0)
+
+	sink.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	if err := sink.Flush(); err == nil {
+		t.Error("expected an error for a rejected push")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected a rejected push not to be retried, got %d requests", n)
+	}
+}
+
+func TestSink_metadataKeys(t *testing.T) {
+	levels := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Streams []struct {
+				Stream map[string]string `json:"stream"`
+			} `json:"streams"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, s := range body.Streams {
+			levels <- s.Stream["level"]
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := loki.NewSink(server.URL, loki.WithFlushInterval(0))
+	defer sink.Close()
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Error(ctx)
+	logs.Print(ctx, logs.WithOutput(sink), logs.WithMetadataKeys("severity", "timestamp"))
+
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if level := <-levels; level != "error" {
+		t.Errorf("expected the level label to be error, got %q", level)
+	}
+}
+
+func TestSink_bufferSize(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := loki.NewSink(server.URL, loki.WithBatchSize(1), loki.WithBufferSize(2))
+	defer sink.Close()
+
+	sink.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	<-started
+
+	for range 3 {
+		sink.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	}
+	close(release)
+
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := sink.Dropped(); n != 1 {
+		t.Errorf("expected 1 log entry to be dropped while the push was waiting, got %d", n)
+	}
+}
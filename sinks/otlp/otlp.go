@@ -0,0 +1,443 @@
+// Package otlp provides an output for the logs package that exports log entries
+// as OpenTelemetry log records, using the OTLP protocol over gRPC or HTTP.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rclark/logs"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protocol is the transport used to export log records.
+type Protocol int
+
+const (
+	// GRPC exports log records using OTLP/gRPC, to an endpoint such as
+	// "localhost:4317".
+	GRPC Protocol = iota
+	// HTTP exports log records using OTLP/HTTP with protobuf encoding, to an
+	// endpoint such as "http://localhost:4318/v1/logs".
+	HTTP
+)
+
+// Exporter is an io.Writer that converts each log entry to an OpenTelemetry
+// log record, collects the records into batches, and exports them to an OTLP
+// endpoint, such as an OpenTelemetry Collector, from a background goroutine.
+// Use it with logs.WithOutput or logs.Output. It is safe for concurrent use.
+//
+// A log entry's level sets the record's severity. The level is passed to the
+// exporter by the logs package when printing, since the exporter is a
+// logs.LevelWriter; data written using [Exporter.Write] takes its level from
+// an "@level" field, if there is one. A log entry's message field, as
+// set by logs.Msg, becomes the record's body. The log entry's other fields are
+// flattened using dot notation and become the record's attributes. The
+// "@time" field sets the record's timestamp, and the "trace_id" and "span_id"
+// fields added by otellog.WithTrace set its trace context.
+type Exporter struct {
+	protocol Protocol
+	endpoint string
+	options  options
+	conn     *grpc.ClientConn
+	client   collogs.LogsServiceClient
+
+	mu      sync.Mutex
+	batch   []record
+	closed  bool
+	full    chan struct{}
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	errMu sync.Mutex
+	err   error
+}
+
+type options struct {
+	resource    map[string]string
+	messageKey  string
+	headers     map[string]string
+	httpClient  *http.Client
+	dialOptions []grpc.DialOption
+	insecure    bool
+	timeout     time.Duration
+	interval    time.Duration
+	batchSize   int
+	attempts    int
+	backoff     time.Duration
+}
+
+// Option is a configuration option for an [Exporter].
+type Option func(*options)
+
+// WithServiceName sets the "service.name" resource attribute of the exported
+// log records.
+func WithServiceName(name string) Option {
+	return WithResource(map[string]string{"service.name": name})
+}
+
+// WithResource adds attributes to the resource that the exported log records
+// describe.
+func WithResource(attrs map[string]string) Option {
+	return func(o *options) {
+		for k, v := range attrs {
+			o.resource[k] = v
+		}
+	}
+}
+
+// WithMessageKey sets the field of the log entry that becomes the record's
+// body. It should match the key set using logs.WithMessageKey. The default is
+// "message".
+func WithMessageKey(key string) Option {
+	return func(o *options) {
+		o.messageKey = key
+	}
+}
+
+// WithHeaders adds headers, or gRPC metadata, to each export request, such as
+// for authentication.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		for k, v := range headers {
+			o.headers[k] = v
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to export log records using
+// [HTTP]. The default is an http.Client with no timeout of its own; each
+// request is limited by the export timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithDialOptions sets the options used to create the gRPC client when
+// exporting log records using [GRPC]. By default, the connection uses TLS with
+// the system's root certificates; pass grpc.WithTransportCredentials to
+// configure TLS differently.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = opts
+	}
+}
+
+// WithInsecure configures the exporter to connect without TLS when exporting
+// log records using [GRPC], such as to a collector running on the same host.
+func WithInsecure() Option {
+	return func(o *options) {
+		o.insecure = true
+	}
+}
+
+// WithTimeout sets the time limit for each export request. The default is 10
+// seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithFlushInterval sets how often the exporter exports the log records it has
+// collected. The default, which is also kept if d is not positive, is one
+// second.
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// WithBatchSize sets the number of log records that the exporter collects
+// before exporting them, without waiting for the flush interval. The default
+// is 512.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithRetry sets how many times the exporter attempts to export a batch, and
+// how long it waits before the first retry. The wait doubles after each retry.
+// A batch is retried if the endpoint is unavailable or asks the exporter to
+// slow down. The default is 3 attempts with a backoff of 500ms.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// NewExporter creates an [Exporter] that exports log records to the endpoint
+// using the protocol. Call [Exporter.Close] to export any remaining log records
+// and stop the background goroutine.
+func NewExporter(protocol Protocol, endpoint string, opts ...Option) (*Exporter, error) {
+	o := options{
+		resource:   map[string]string{},
+		messageKey: "message",
+		headers:    map[string]string{},
+		httpClient: &http.Client{},
+		timeout:    10 * time.Second,
+		interval:   time.Second,
+		batchSize:  512,
+		attempts:   3,
+		backoff:    500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	e := &Exporter{
+		protocol: protocol,
+		endpoint: endpoint,
+		options:  o,
+		full:     make(chan struct{}, 1),
+		flushes:  make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	switch protocol {
+	case GRPC:
+		creds := credentials.NewClientTLSFromCert(nil, "")
+		if o.insecure {
+			creds = insecure.NewCredentials()
+		}
+
+		dialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, o.dialOptions...)
+		conn, err := grpc.NewClient(endpoint, dialOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP gRPC client: %w", err)
+		}
+
+		e.conn = conn
+		e.client = collogs.NewLogsServiceClient(conn)
+	case HTTP:
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol: %d", protocol)
+	}
+
+	go e.run()
+	return e, nil
+}
+
+// Write converts each line of p to a log record and adds it to the batch
+// waiting to be exported, reading the level of each from its "@level" field.
+// It returns logs.ErrWriterClosed if the exporter has been closed.
+func (e *Exporter) Write(p []byte) (int, error) {
+	return e.add(p, record{})
+}
+
+// WriteLevel converts each line of p to a log record with the given level, and
+// adds it to the batch waiting to be exported, as [Exporter.Write] does.
+func (e *Exporter) WriteLevel(level logs.Level, p []byte) (int, error) {
+	return e.add(p, record{level: level, leveled: true})
+}
+
+func (e *Exporter) add(p []byte, r record) (int, error) {
+	r.observed = time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return 0, logs.ErrWriterClosed
+	}
+
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		r.line = bytes.Clone(line)
+		e.batch = append(e.batch, r)
+	}
+
+	if len(e.batch) >= e.options.batchSize {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush exports the log records written before the call. It returns the first
+// error encountered while exporting since the previous call to Flush.
+func (e *Exporter) Flush() error {
+	e.mu.Lock()
+	closed := e.closed
+	e.mu.Unlock()
+
+	if !closed {
+		flushed := make(chan struct{})
+		select {
+		case e.flushes <- flushed:
+			<-flushed
+		case <-e.done:
+		}
+	}
+
+	return e.takeErr()
+}
+
+// Close stops accepting writes, exports any remaining log records, and stops
+// the background goroutine. It returns the first error encountered while
+// exporting since the last call to Flush.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.stop)
+	}
+	e.mu.Unlock()
+
+	<-e.done
+
+	if e.conn != nil {
+		e.conn.Close()
+	}
+
+	return e.takeErr()
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.options.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.push()
+		case <-e.full:
+			e.push()
+		case flushed := <-e.flushes:
+			e.push()
+			close(flushed)
+		case <-e.stop:
+			e.push()
+			return
+		}
+	}
+}
+
+// push exports the collected log records, retrying as configured.
+func (e *Exporter) push() {
+	e.mu.Lock()
+	batch := e.batch
+	e.batch = nil
+	e.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	req := newRequest(batch, e.options)
+
+	backoff := e.options.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := e.send(req)
+		if err == nil {
+			return
+		}
+
+		if !retry || attempt >= e.options.attempts {
+			e.setErr(fmt.Errorf("failed to export %d log records: %w", len(batch), err))
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// send makes a single export request. It reports whether a failed request
+// should be retried.
+func (e *Exporter) send(req *collogs.ExportLogsServiceRequest) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.options.timeout)
+	defer cancel()
+
+	if e.protocol == GRPC {
+		if len(e.options.headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.options.headers))
+		}
+
+		_, err := e.client.Export(ctx, req)
+		switch status.Code(err) {
+		case codes.OK:
+			return false, nil
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+			return true, err
+		default:
+			return false, err
+		}
+	}
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode OTLP request: %w", err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.options.headers {
+		r.Header.Set(k, v)
+	}
+
+	resp, err := e.options.httpClient.Do(r)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return false, nil
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
+
+func (e *Exporter) setErr(err error) {
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *Exporter) takeErr() error {
+	e.errMu.Lock()
+	defer e.errMu.Unlock()
+
+	err := e.err
+	e.err = nil
+	return err
+}
@@ -0,0 +1,180 @@
+package otlp_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rclark/logs"
+	"github.com/rclark/logs/sinks/otlp"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func ExampleNewExporter() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		var req collogs.ExportLogsServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, rl := range req.ResourceLogs {
+			for _, attr := range rl.Resource.Attributes {
+				fmt.Printf("resource %s=%s\n", attr.Key, attr.Value.GetStringValue())
+			}
+			for _, sl := range rl.ScopeLogs {
+				for _, lr := range sl.LogRecords {
+					fmt.Println(lr.SeverityNumber, lr.SeverityText, lr.Body.GetStringValue())
+					for _, attr := range lr.Attributes {
+						fmt.Printf("  %s=%v\n", attr.Key, value(attr.Value))
+					}
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	exporter, err := otlp.NewExporter(otlp.HTTP, server.URL+"/v1/logs", otlp.WithServiceName("api"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Msg(ctx, "user not found")
+	logs.Add(ctx, "http.status", 404, "http.path", "/users/42")
+	logs.Warn(ctx)
+	logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOutput(exporter))
+
+	if err := exporter.Close(); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// resource service.name=api
+	// SEVERITY_NUMBER_WARN WARN user not found
+	//   http.path=/users/42
+	//   http.status=404
+}
+
+// value unwraps a string or integer attribute value for printing.
+func value(v *commonpb.AnyValue) any {
+	if s, ok := v.Value.(*commonpb.AnyValue_StringValue); ok {
+		return s.StringValue
+	}
+	return v.GetIntValue()
+}
+
+type collector struct {
+	collogs.UnimplementedLogsServiceServer
+	requests chan *collogs.ExportLogsServiceRequest
+}
+
+func (c *collector) Export(ctx context.Context, req *collogs.ExportLogsServiceRequest) (*collogs.ExportLogsServiceResponse, error) {
+	c.requests <- req
+	return &collogs.ExportLogsServiceResponse{}, nil
+}
+
+func TestExporter_grpc(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &collector{requests: make(chan *collogs.ExportLogsServiceRequest, 1)}
+	server := grpc.NewServer()
+	collogs.RegisterLogsServiceServer(server, c)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	exporter, err := otlp.NewExporter(otlp.GRPC, lis.Addr().String(), otlp.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close()
+
+	exporter.Write([]byte(`{"@level":"ERROR","@time":"2024-01-02T03:04:05Z","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01","db":{"rows":3}}` + "\n"))
+	if err := exporter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lr := (<-c.requests).ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if lr.SeverityText != "ERROR" || lr.SeverityNumber != 17 {
+		t.Errorf("expected ERROR severity, got %s (%d)", lr.SeverityText, lr.SeverityNumber)
+	}
+	if want := uint64(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()); lr.TimeUnixNano != want {
+		t.Errorf("expected time %d, got %d", want, lr.TimeUnixNano)
+	}
+	if fmt.Sprintf("%x/%x/%d", lr.TraceId, lr.SpanId, lr.Flags) != "4bf92f3577b34da6a3ce929d0e0e4736/00f067aa0ba902b7/1" {
+		t.Errorf("unexpected trace context %x/%x/%d", lr.TraceId, lr.SpanId, lr.Flags)
+	}
+	if len(lr.Attributes) != 1 || lr.Attributes[0].Key != "db.rows" || lr.Attributes[0].Value.GetIntValue() != 3 {
+		t.Errorf("expected a single db.rows attribute, got %v", lr.Attributes)
+	}
+}
+
+func TestExporter_metadataKeys(t *testing.T) {
+	records := make(chan *collogs.ExportLogsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		var req collogs.ExportLogsServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		records <- &req
+	}))
+	defer server.Close()
+
+	exporter, err := otlp.NewExporter(otlp.HTTP, server.URL, otlp.WithFlushInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close()
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Error(ctx)
+	logs.Print(ctx, logs.WithOutput(exporter), logs.WithMetadataKeys("severity", "timestamp"))
+	if err := exporter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lr := (<-records).ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if lr.SeverityText != "ERROR" || lr.SeverityNumber != 17 {
+		t.Errorf("expected ERROR severity, got %s (%d)", lr.SeverityText, lr.SeverityNumber)
+	}
+}
+
+func TestExporter_tls(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &collector{requests: make(chan *collogs.ExportLogsServiceRequest, 1)}
+	server := grpc.NewServer()
+	collogs.RegisterLogsServiceServer(server, c)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	exporter, err := otlp.NewExporter(otlp.GRPC, lis.Addr().String(), otlp.WithRetry(1, 0), otlp.WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close()
+
+	exporter.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	if err := exporter.Flush(); err == nil {
+		t.Error("expected the default TLS connection to a server without TLS to fail")
+	}
+}
@@ -0,0 +1,195 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/rclark/logs"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// scopeName identifies this package as the source of the exported records.
+const scopeName = "github.com/rclark/logs/sinks/otlp"
+
+// record is a log entry waiting to be exported. The level is set if it was
+// passed to [Exporter.WriteLevel].
+type record struct {
+	observed time.Time
+	line     []byte
+	level    logs.Level
+	leveled  bool
+}
+
+// newRequest builds an export request holding the batch of log entries.
+func newRequest(batch []record, o options) *collogs.ExportLogsServiceRequest {
+	records := make([]*logspb.LogRecord, 0, len(batch))
+	for _, r := range batch {
+		records = append(records, toLogRecord(r, o.messageKey))
+	}
+
+	resource := &resourcepb.Resource{}
+	for _, k := range sortedKeys(o.resource) {
+		resource.Attributes = append(resource.Attributes, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: o.resource[k]}},
+		})
+	}
+
+	return &collogs.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: scopeName},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+// toLogRecord converts a log entry to a log record. A line that is not a JSON
+// object becomes the body of a record, whose severity is only set if the level
+// was passed along with it.
+func toLogRecord(r record, messageKey string) *logspb.LogRecord {
+	lr := &logspb.LogRecord{ObservedTimeUnixNano: uint64(r.observed.UnixNano())}
+	if r.leveled {
+		lr.SeverityNumber = severity(r.level)
+		lr.SeverityText = r.level.String()
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(r.line))
+	dec.UseNumber()
+
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		lr.Body = stringValue(string(r.line))
+		return lr
+	}
+
+	if name, ok := m["@level"].(string); ok {
+		if level, err := logs.ParseLevel(name); err == nil && !r.leveled {
+			lr.SeverityNumber = severity(level)
+			lr.SeverityText = level.String()
+		}
+		delete(m, "@level")
+	}
+
+	if s, ok := m["@time"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil && !t.IsZero() {
+			lr.TimeUnixNano = uint64(t.UnixNano())
+		}
+		delete(m, "@time")
+	}
+
+	if msg, ok := m[messageKey]; ok {
+		lr.Body = anyValue(msg)
+		delete(m, messageKey)
+	}
+
+	if id, err := hex.DecodeString(stringField(m, "trace_id")); err == nil && len(id) == 16 {
+		lr.TraceId = id
+		delete(m, "trace_id")
+	}
+
+	if id, err := hex.DecodeString(stringField(m, "span_id")); err == nil && len(id) == 8 {
+		lr.SpanId = id
+		delete(m, "span_id")
+	}
+
+	if flags, err := hex.DecodeString(stringField(m, "trace_flags")); err == nil && len(flags) == 1 {
+		lr.Flags = uint32(flags[0])
+		delete(m, "trace_flags")
+	}
+
+	attrs := map[string]any{}
+	flatten("", m, attrs)
+	for _, k := range sortedKeys(attrs) {
+		lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: k, Value: anyValue(attrs[k])})
+	}
+
+	return lr
+}
+
+// severity maps a level to a severity number. The built-in levels map to the
+// base severity of the same name, and custom levels to the severity between
+// them that is nearest to their position.
+func severity(level logs.Level) logspb.SeverityNumber {
+	n := 1 + int(level)*4/10
+	return logspb.SeverityNumber(max(1, min(n, 24)))
+}
+
+// flatten copies the fields of the map into dst, naming nested fields using dot
+// notation.
+func flatten(prefix string, m map[string]any, dst map[string]any) {
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+
+		if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+			flatten(k, nested, dst)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
+
+// anyValue converts a decoded JSON value to an attribute value.
+func anyValue(v any) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return stringValue(v)
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: n}}
+		}
+		if f, err := v.Float64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+		}
+		return stringValue(v.String())
+	case []any:
+		values := make([]*commonpb.AnyValue, 0, len(v))
+		for _, item := range v {
+			values = append(values, anyValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]any:
+		kvs := make([]*commonpb.KeyValue, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			kvs = append(kvs, &commonpb.KeyValue{Key: k, Value: anyValue(v[k])})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: kvs}}}
+	default:
+		return &commonpb.AnyValue{}
+	}
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+// stringField returns the value of a string field of the map, or an empty
+// string if there is no such field.
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
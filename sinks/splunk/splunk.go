@@ -0,0 +1,474 @@
+// Package splunk provides an output for the logs package that sends log entries
+// to a Splunk HTTP Event Collector.
+package splunk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclark/logs"
+)
+
+// Sink is an io.Writer that wraps each log entry in an HTTP Event Collector
+// event, collects the events into batches, and sends them to Splunk from a
+// background goroutine. Use it with logs.WithOutput or logs.Output. It is safe
+// for concurrent use.
+//
+// Each log entry is sent as the event's "event" field. The event is timestamped
+// with the time of the log entry, which is passed to the sink by the logs
+// package when printing, since the sink is a logs.MetadataWriter; data written
+// using [Sink.Write] is timestamped when it is written. A line that is not
+// valid JSON is sent as a string.
+type Sink struct {
+	endpoint string
+	token    string
+	options  options
+
+	mu      sync.Mutex
+	batch   []event
+	closed  bool
+	dropped atomic.Uint64
+	full    chan struct{}
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	errMu sync.Mutex
+	err   error
+}
+
+// event is an HTTP Event Collector event.
+type event struct {
+	Time       float64         `json:"time"`
+	Event      json.RawMessage `json:"event"`
+	Host       string          `json:"host,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Sourcetype string          `json:"sourcetype,omitempty"`
+	Index      string          `json:"index,omitempty"`
+}
+
+type options struct {
+	client     *http.Client
+	host       string
+	source     string
+	sourcetype string
+	index      string
+	interval   time.Duration
+	batchSize  int
+	buffered   int
+	attempts   int
+	backoff    time.Duration
+	channel    string
+	ackTimeout time.Duration
+}
+
+// Option is a configuration option for a [Sink].
+type Option func(*options)
+
+// WithSourcetype sets the sourcetype of the events. The default is "_json".
+func WithSourcetype(sourcetype string) Option {
+	return func(o *options) {
+		o.sourcetype = sourcetype
+	}
+}
+
+// WithIndex sets the index that the events are written to. By default, they
+// are written to the default index of the token.
+func WithIndex(index string) Option {
+	return func(o *options) {
+		o.index = index
+	}
+}
+
+// WithSource sets the source of the events.
+func WithSource(source string) Option {
+	return func(o *options) {
+		o.source = source
+	}
+}
+
+// WithHost sets the host of the events. By default, Splunk uses the host that
+// sent them.
+func WithHost(host string) Option {
+	return func(o *options) {
+		o.host = host
+	}
+}
+
+// WithFlushInterval sets how often the sink sends the events it has collected.
+// The default, which is also kept if d is not positive, is one second.
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// WithBatchSize sets the number of events that the sink collects before
+// sending them, without waiting for the flush interval. The default is 100.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithBufferSize sets the number of events that the sink holds while it waits
+// to send them, such as while a batch is being retried. Once the buffer is
+// full, further events are dropped and counted by [Sink.Dropped]. The default
+// is 10000.
+func WithBufferSize(n int) Option {
+	return func(o *options) {
+		o.buffered = n
+	}
+}
+
+// WithRetry sets how many times the sink attempts to send a batch, and how
+// long it waits before the first retry. The wait doubles after each retry. A
+// batch is retried if the request fails, if Splunk responds with a 429 or 5xx
+// status, or if it is not acknowledged in time. The default is 3 attempts with
+// a backoff of 500ms.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// WithAcknowledgment configures the sink for a token that has indexer
+// acknowledgment enabled. After each batch is sent, the sink waits until
+// Splunk acknowledges that it has been indexed, and sends it again if it is not
+// acknowledged within the timeout. The channel identifies the sink to Splunk
+// and must be a GUID; if it is empty, a random one is used.
+func WithAcknowledgment(channel string, timeout time.Duration) Option {
+	return func(o *options) {
+		if channel == "" {
+			channel = newChannel()
+		}
+
+		o.channel = channel
+		o.ackTimeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send events. The default is an
+// http.Client with a timeout of 10 seconds.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// NewSink creates a [Sink] that sends events to the HTTP Event Collector at
+// endpoint, such as "https://splunk.example.com:8088", using the token. Call
+// [Sink.Close] to send any remaining events and stop the background goroutine.
+func NewSink(endpoint, token string, opts ...Option) *Sink {
+	o := options{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		sourcetype: "_json",
+		interval:   time.Second,
+		batchSize:  100,
+		buffered:   10000,
+		attempts:   3,
+		backoff:    500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Sink{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		options:  o,
+		full:     make(chan struct{}, 1),
+		flushes:  make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go s.run()
+	return s
+}
+
+// Write wraps each line of p in an event and adds it to the batch waiting to be
+// sent. It returns logs.ErrWriterClosed if the sink has been closed. A line
+// that is dropped because the buffer is full is not reported as an error, but
+// is counted by [Sink.Dropped].
+func (s *Sink) Write(p []byte) (int, error) {
+	return s.add(p, time.Now())
+}
+
+// WriteMetadata wraps each line of p in an event, as [Sink.Write] does,
+// timestamped with the time in the metadata.
+func (s *Sink) WriteMetadata(meta logs.Metadata, p []byte) (int, error) {
+	return s.add(p, meta.Time)
+}
+
+func (s *Sink) add(p []byte, t time.Time) (int, error) {
+	stamp := float64(t.UnixMilli()) / 1000
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, logs.ErrWriterClosed
+	}
+
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		if len(s.batch) >= s.options.buffered {
+			s.dropped.Add(1)
+			continue
+		}
+
+		body := json.RawMessage(bytes.Clone(line))
+		if !json.Valid(body) {
+			body, _ = json.Marshal(string(line))
+		}
+
+		s.batch = append(s.batch, event{
+			Time:       stamp,
+			Event:      body,
+			Host:       s.options.host,
+			Source:     s.options.source,
+			Sourcetype: s.options.sourcetype,
+			Index:      s.options.index,
+		})
+	}
+
+	if len(s.batch) >= s.options.batchSize {
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Dropped returns the number of events that were dropped because the buffer
+// was full.
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Flush sends the events written before the call, and waits for them to be
+// acknowledged if acknowledgment is enabled. It returns the first error
+// encountered while sending since the previous call to Flush.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+
+	if !closed {
+		flushed := make(chan struct{})
+		select {
+		case s.flushes <- flushed:
+			<-flushed
+		case <-s.done:
+		}
+	}
+
+	return s.takeErr()
+}
+
+// Close stops accepting writes, sends any remaining events, and stops the
+// background goroutine. It returns the first error encountered while sending
+// since the last call to Flush.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+	return s.takeErr()
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.options.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.push()
+		case <-s.full:
+			s.push()
+		case flushed := <-s.flushes:
+			s.push()
+			close(flushed)
+		case <-s.stop:
+			s.push()
+			return
+		}
+	}
+}
+
+// push sends the collected events to Splunk, retrying as configured.
+func (s *Sink) push() {
+	s.mu.Lock()
+	batch := s.batch
+	s.batch = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range batch {
+		if err := enc.Encode(e); err != nil {
+			s.setErr(fmt.Errorf("failed to encode splunk event: %w", err))
+			return
+		}
+	}
+
+	backoff := s.options.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.send(body.Bytes())
+		if err == nil {
+			return
+		}
+
+		if !retry || attempt >= s.options.attempts {
+			s.setErr(fmt.Errorf("failed to send %d events to splunk: %w", len(batch), err))
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// response is the body of an HTTP Event Collector response.
+type response struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+// send makes a single request to the event endpoint, and waits for it to be
+// acknowledged if acknowledgment is enabled. It reports whether a failed
+// request should be retried.
+func (s *Sink) send(body []byte) (bool, error) {
+	var resp response
+	status, err := s.post("/services/collector/event", body, &resp)
+	if err != nil {
+		return true, err
+	}
+
+	if status/100 != 2 {
+		err := fmt.Errorf("unexpected status %d: %s (code %d)", status, resp.Text, resp.Code)
+		return status == http.StatusTooManyRequests || status >= 500, err
+	}
+
+	if s.options.channel == "" {
+		return false, nil
+	}
+
+	if resp.AckID == nil {
+		return false, errors.New("acknowledgment is not enabled for the token")
+	}
+
+	return true, s.waitForAck(*resp.AckID)
+}
+
+// waitForAck polls the acknowledgment endpoint until the request with the ID
+// has been indexed, or the acknowledgment timeout passes.
+func (s *Sink) waitForAck(id int64) error {
+	body, _ := json.Marshal(map[string][]int64{"acks": {id}})
+	deadline := time.Now().Add(s.options.ackTimeout)
+	wait := 100 * time.Millisecond
+
+	for {
+		var resp struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		status, err := s.post("/services/collector/ack", body, &resp)
+		if err == nil && status/100 == 2 && resp.Acks[fmt.Sprint(id)] {
+			return nil
+		}
+
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("request %d was not acknowledged within %s", id, s.options.ackTimeout)
+		}
+
+		time.Sleep(wait)
+		wait = min(wait*2, time.Second)
+	}
+}
+
+// post makes a request to the HTTP Event Collector and decodes its JSON
+// response into v.
+func (s *Sink) post(path string, body []byte, v any) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, s.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+	if s.options.channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", s.options.channel)
+	}
+
+	resp, err := s.options.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return 0, err
+	}
+
+	json.Unmarshal(data, v)
+	return resp.StatusCode, nil
+}
+
+func (s *Sink) setErr(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *Sink) takeErr() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// newChannel creates a random GUID to identify the sink to Splunk.
+func newChannel() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
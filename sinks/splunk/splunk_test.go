@@ -0,0 +1,174 @@
+package splunk_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclark/logs"
+	"github.com/rclark/logs/sinks/splunk"
+)
+
+func ExampleNewSink() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Println(r.URL.Path, r.Header.Get("Authorization"))
+
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var e struct {
+				Event      json.RawMessage `json:"event"`
+				Sourcetype string          `json:"sourcetype"`
+				Index      string          `json:"index"`
+			}
+			if err := dec.Decode(&e); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Println(e.Index, e.Sourcetype, string(e.Event))
+		}
+
+		fmt.Fprint(w, `{"text":"Success","code":0}`)
+	}))
+	defer server.Close()
+
+	sink := splunk.NewSink(server.URL, "my-token", splunk.WithIndex("web"))
+
+	for _, path := range []string{"/users", "/orders"} {
+		ctx := logs.AddEntry(context.Background())
+		logs.Add(ctx, "path", path)
+		logs.Print(ctx, logs.WithCurrentTime(time.Time{}), logs.WithOutput(sink))
+	}
+
+	if err := sink.Close(); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// /services/collector/event Splunk my-token
+	// web _json {"@level":"INFO","@time":"0001-01-01T00:00:00Z","path":"/users"}
+	// web _json {"@level":"INFO","@time":"0001-01-01T00:00:00Z","path":"/orders"}
+}
+
+func TestSink_acknowledgment(t *testing.T) {
+	var sends, polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Splunk-Request-Channel") != "0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"text":"Data channel is missing","code":10}`)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/services/collector/event":
+			fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, sends.Add(1))
+		case "/services/collector/ack":
+			// The first request is never acknowledged, and the second is
+			// acknowledged on the second poll.
+			acked := sends.Load() == 2 && polls.Add(1) > 1
+			fmt.Fprintf(w, `{"acks":{"%d":%t}}`, sends.Load(), acked)
+		}
+	}))
+	defer server.Close()
+
+	sink := splunk.NewSink(server.URL, "my-token",
+		splunk.WithAcknowledgment("0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba", 250*time.Millisecond),
+		splunk.WithRetry(2, time.Millisecond),
+	)
+	defer sink.Close()
+
+	sink.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("expected the batch to be acknowledged on the second attempt, got %v", err)
+	}
+	if n := sends.Load(); n != 2 {
+		t.Errorf("expected the unacknowledged batch to be sent again, got %d sends", n)
+	}
+}
+
+func TestSink_flushInterval(t *testing.T) {
+	var sends atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sends.Add(1)
+		fmt.Fprint(w, `{"text":"Success","code":0}`)
+	}))
+	defer server.Close()
+
+	sink := splunk.NewSink(server.URL, "my-token", splunk.WithFlushInterval(0))
+
+	sink.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := sends.Load(); n != 1 {
+		t.Errorf("expected the event to be sent on close, got %d sends", n)
+	}
+}
+
+func TestSink_bufferSize(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		fmt.Fprint(w, `{"text":"Success","code":0}`)
+	}))
+	defer server.Close()
+
+	sink := splunk.NewSink(server.URL, "my-token", splunk.WithBatchSize(1), splunk.WithBufferSize(2))
+	defer sink.Close()
+
+	sink.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	<-started
+
+	for range 3 {
+		sink.Write([]byte(`{"@level":"INFO"}` + "\n"))
+	}
+	close(release)
+
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := sink.Dropped(); n != 1 {
+		t.Errorf("expected 1 event to be dropped while the batch was being sent, got %d", n)
+	}
+}
+
+func TestSink_time(t *testing.T) {
+	var times []float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var e struct {
+				Time float64 `json:"time"`
+			}
+			if err := dec.Decode(&e); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			times = append(times, e.Time)
+		}
+
+		fmt.Fprint(w, `{"text":"Success","code":0}`)
+	}))
+	defer server.Close()
+
+	sink := splunk.NewSink(server.URL, "my-token")
+
+	ctx := logs.AddEntry(context.Background())
+	logs.Print(ctx, logs.WithCurrentTime(time.UnixMilli(1700000000250)), logs.WithOutput(sink))
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 1 || times[0] != 1700000000.25 {
+		t.Errorf("expected the event to be timestamped with the log entry's time, got %v", times)
+	}
+}
@@ -0,0 +1,133 @@
+package logs
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler is a [slog.Handler] that writes log records into the freeform log
+// entry in the context, rather than printing them as separate lines. This lets
+// libraries that log using slog contribute to the context's log entry.
+//
+// Each record's attributes are added to the log entry, with groups written as
+// nested keys. Each record's message is appended to the log entry's "messages"
+// key. If a record's level is higher than the log entry's, the log entry's
+// level is raised to match. Records logged with a context that has no freeform
+// log entry are discarded.
+type SlogHandler struct {
+	level  slog.Leveler
+	prefix string
+	attrs  keyValues
+}
+
+// NewSlogHandler creates a new [SlogHandler] that handles records at or above
+// the given level. If level is nil, records at or above slog.LevelInfo are
+// handled.
+func NewSlogHandler(level slog.Leveler) *SlogHandler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+
+	return &SlogHandler{level: level}
+}
+
+// Enabled reports whether the handler handles records at the given level. It
+// will return false if there is no freeform log entry in the context.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if ctx == nil || getEntry[FreeformEntry](ctx) == nil {
+		return false
+	}
+
+	return level >= h.level.Level()
+}
+
+// Handle writes the record into the freeform log entry in the context.
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx == nil {
+		return nil
+	}
+
+	entry := getMutableEntry[FreeformEntry](ctx)
+	if entry == nil {
+		return nil
+	}
+
+	kvs := append(keyValues{}, h.attrs...)
+	record.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, h.prefix, a)
+		return true
+	})
+	kvs.adjust(*entry.data)
+
+	if record.Message != "" {
+		appendValues(ctx, "messages", Promote, record.Message)
+	}
+
+	if level := slogLevel(record.Level); level > entry.currentLevel() {
+		entry.setLevel(level)
+	}
+
+	return nil
+}
+
+// WithAttrs returns a new handler that adds the attributes to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append(keyValues{}, h.attrs...)
+	for _, a := range attrs {
+		next.attrs = appendAttr(next.attrs, h.prefix, a)
+	}
+
+	return &next
+}
+
+// WithGroup returns a new handler that nests the attributes of every record
+// under the group name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// appendAttr converts an attribute into key-values, flattening groups into
+// dot-notation keys.
+func appendAttr(kvs keyValues, prefix string, a slog.Attr) keyValues {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix = prefix + a.Key + "."
+		}
+
+		for _, ga := range a.Value.Group() {
+			kvs = appendAttr(kvs, prefix, ga)
+		}
+
+		return kvs
+	}
+
+	return append(kvs, keyValue{Key: prefix + a.Key, Value: a.Value.Any()})
+}
+
+// slogLevel converts a slog level into the closest log level.
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level >= slog.LevelError:
+		return ERROR
+	case level >= slog.LevelWarn:
+		return WARN
+	case level >= slog.LevelInfo:
+		return INFO
+	case level >= slog.LevelDebug:
+		return DEBUG
+	default:
+		return TRACE
+	}
+}
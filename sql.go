@@ -0,0 +1,301 @@
+package logs
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+// SqlData is the data structure that [WrapDriver] appends to the "@sql" key of
+// a log entry for each query.
+type SqlData struct {
+	Query        string        `json:"query"`
+	Args         []any         `json:"args,omitempty"`
+	RowsAffected *int64        `json:"rows_affected,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	Duration     time.Duration `json:"duration"`
+}
+
+// WithQueryArgs configures [WrapDriver] to record the arguments of each query.
+// Each argument is passed to redact, which returns the value to record, so
+// that sensitive values can be replaced with [Redacted]. If redact is nil, the
+// arguments are recorded as they are.
+func WithQueryArgs(redact func(arg driver.NamedValue) any) MiddlewareOption {
+	return func(o *option) {
+		o.queryArgs = true
+		o.argRedactor = redact
+	}
+}
+
+// WrapDriver wraps a database/sql driver so that each query is recorded in the
+// freeform log entry in the query's context. The query, the number of rows it
+// affected, and its duration are appended to the "@sql" key, so that a single
+// log entry shows all of the database activity of a request. Queries made
+// without a context, or whose context has no freeform log entry, are passed on
+// without being recorded. Queries may be made concurrently with the same
+// context.
+//
+// Register the wrapped driver under a new name and open it as usual:
+//
+//	sql.Register("postgres+logs", logs.WrapDriver(&pq.Driver{}))
+//	db, err := sql.Open("postgres+logs", dsn)
+func WrapDriver(d driver.Driver, opts ...MiddlewareOption) driver.Driver {
+	return &sqlDriver{next: d, opt: applyOptions(opts...)}
+}
+
+// WrapConnector wraps a database/sql connector, for use with sql.OpenDB, so
+// that each query is recorded as described by [WrapDriver].
+func WrapConnector(c driver.Connector, opts ...MiddlewareOption) driver.Connector {
+	d := &sqlDriver{next: c.Driver(), opt: applyOptions(opts...)}
+	return &sqlConnector{next: c, driver: d}
+}
+
+// sqlDriver is a driver.Driver that records queries.
+type sqlDriver struct {
+	next driver.Driver
+	opt  option
+}
+
+// Open opens a connection using the wrapped driver.
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.next.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlConn{Conn: c, opt: d.opt}, nil
+}
+
+// OpenConnector creates a connector using the wrapped driver, if it supports
+// connectors, or one that calls Open otherwise.
+func (d *sqlDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.next.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return &sqlConnector{next: c, driver: d}, nil
+	}
+
+	return &sqlConnector{name: name, driver: d}, nil
+}
+
+// sqlConnector is a driver.Connector that records queries.
+type sqlConnector struct {
+	next   driver.Connector
+	name   string
+	driver *sqlDriver
+}
+
+// Connect opens a connection using the wrapped connector.
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.next == nil {
+		return c.driver.Open(c.name)
+	}
+
+	conn, err := c.next.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlConn{Conn: conn, opt: c.driver.opt}, nil
+}
+
+// Driver returns the wrapped driver.
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqlConn is a driver.Conn that records queries. It implements the optional
+// interfaces of database/sql by passing calls on to the wrapped connection,
+// falling back to the behavior that database/sql would use without them.
+type sqlConn struct {
+	driver.Conn
+	opt option
+}
+
+// Prepare prepares a statement using the wrapped connection.
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext prepares a statement using the wrapped connection.
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = pc.PrepareContext(ctx, query)
+	} else {
+		s, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlStmt{Stmt: s, conn: c, query: query}, nil
+}
+
+// BeginTx starts a transaction using the wrapped connection.
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bt.BeginTx(ctx, opts)
+	}
+
+	return c.Conn.Begin()
+}
+
+// ExecContext executes a query using the wrapped connection, and records it.
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := c.opt.timer.Now()
+	result, err := ec.ExecContext(ctx, query, args)
+	c.record(ctx, query, args, result, err, start)
+	return result, err
+}
+
+// QueryContext executes a query using the wrapped connection, and records it.
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := c.opt.timer.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	c.record(ctx, query, args, nil, err, start)
+	return rows, err
+}
+
+// Ping checks the wrapped connection.
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	return nil
+}
+
+// ResetSession resets the wrapped connection.
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+// IsValid reports whether the wrapped connection is valid.
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+
+	return true
+}
+
+// CheckNamedValue checks an argument using the wrapped connection.
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+
+	return driver.ErrSkip
+}
+
+// record appends a query to the freeform log entry in the context, unless the
+// query was skipped or there is no log entry.
+func (c *sqlConn) record(ctx context.Context, query string, args []driver.NamedValue, result driver.Result, err error, start time.Time) {
+	if err == driver.ErrSkip || getEntry[FreeformEntry](ctx) == nil {
+		return
+	}
+
+	data := SqlData{
+		Query:    query,
+		Duration: c.opt.timer.Since(start),
+	}
+
+	if c.opt.queryArgs {
+		data.Args = make([]any, len(args))
+		for i, arg := range args {
+			data.Args[i] = arg.Value
+			if c.opt.argRedactor != nil {
+				data.Args[i] = c.opt.argRedactor(arg)
+			}
+		}
+	}
+
+	if err != nil {
+		data.Error = err.Error()
+	} else if result != nil {
+		if n, err := result.RowsAffected(); err == nil {
+			data.RowsAffected = &n
+		}
+	}
+
+	appendValues(ctx, "@sql", Promote, data)
+}
+
+// sqlStmt is a driver.Stmt that records queries.
+type sqlStmt struct {
+	driver.Stmt
+	conn  *sqlConn
+	query string
+}
+
+// ExecContext executes the statement, and records it.
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := s.conn.opt.timer.Now()
+
+	var result driver.Result
+	var err error
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = ec.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(values(args))
+	}
+
+	s.conn.record(ctx, s.query, args, result, err, start)
+	return result, err
+}
+
+// QueryContext executes the statement, and records it.
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := s.conn.opt.timer.Now()
+
+	var rows driver.Rows
+	var err error
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args))
+	}
+
+	s.conn.record(ctx, s.query, args, nil, err, start)
+	return rows, err
+}
+
+// CheckNamedValue checks an argument using the wrapped statement, or else the
+// wrapped connection.
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+
+	return s.conn.CheckNamedValue(nv)
+}
+
+// values converts named arguments to the positional arguments used by drivers
+// without context support.
+func values(args []driver.NamedValue) []driver.Value {
+	vs := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vs[i] = arg.Value
+	}
+
+	return vs
+}
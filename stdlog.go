@@ -0,0 +1,59 @@
+package logs
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// StdLoggerOption is a configuration option for a logger created by
+// [StdLogger].
+type StdLoggerOption func(*stdWriter)
+
+// WithStdLoggerKey sets the key of the freeform log entry under which a
+// logger created by [StdLogger] appends its messages. The default is
+// "messages".
+func WithStdLoggerKey(key string) StdLoggerOption {
+	return func(w *stdWriter) {
+		w.key = key
+	}
+}
+
+// StdLogger creates a [log.Logger] that writes into the freeform log entry in
+// the context, rather than printing separate lines. Use it to pass to
+// third-party code that takes a [log.Logger], so that its output contributes to
+// the context's log entry. Each message is appended to the log entry's
+// "messages" key, without a timestamp or trailing newline. If the level is
+// higher than the log entry's, the log entry's level is raised to match when a
+// message is written. Messages written when the context has no freeform log
+// entry are discarded.
+func StdLogger(ctx context.Context, level Level, opts ...StdLoggerOption) *log.Logger {
+	w := &stdWriter{ctx: ctx, level: level, key: "messages"}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return log.New(w, "", 0)
+}
+
+// stdWriter receives the output of a logger created by [StdLogger].
+type stdWriter struct {
+	ctx   context.Context
+	level Level
+	key   string
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	entry := getMutableEntry[FreeformEntry](w.ctx)
+	if entry == nil {
+		return len(p), nil
+	}
+
+	appendValues(w.ctx, w.key, Promote, strings.TrimSuffix(string(p), "\n"))
+
+	if w.level > entry.currentLevel() {
+		entry.setLevel(w.level)
+	}
+
+	return len(p), nil
+}
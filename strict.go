@@ -0,0 +1,65 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+var (
+	// ErrNoEntry is returned by functions like [AddE] and [PrintE] when no log
+	// entry of the correct type is found in the context.
+	ErrNoEntry = errors.New("no log entry in context")
+
+	// ErrFinalized is returned by functions like [AddE] and [AdjustE] when the
+	// log entry in the context has been finalized using a function like
+	// [Finalize].
+	ErrFinalized = errors.New("log entry has been finalized")
+
+	// ErrKeyCollision is returned by functions like [AddE] when a key crosses
+	// a value that is not a map, and the log entry's [CollisionPolicy] is
+	// [CollisionError].
+	ErrKeyCollision = errors.New("key collides with an existing value")
+
+	// ErrNotPrinted is returned by functions like [PrintE] when the log entry
+	// is intentionally not printed, such as when its level is below the level
+	// for printing, it is sampled out, or a log entry printed using
+	// [WithOncePrint] has already been printed. The returned error wraps
+	// ErrNotPrinted with the reason.
+	ErrNotPrinted = errors.New("log entry not printed")
+)
+
+var strict atomic.Bool
+
+// SetStrict enables or disables strict mode. In strict mode, functions like
+// [Add], [Print] and [Adjust] panic with the error that their variants like
+// [AddE] would return, rather than returning false. Log entries that are
+// intentionally not printed, as described by [ErrNotPrinted], do not cause a
+// panic. Use this in tests or during development to surface misconfiguration,
+// such as a context that is missing its log entry.
+func SetStrict(enabled bool) {
+	strict.Store(enabled)
+}
+
+// check reports whether err is nil. Otherwise, it panics in strict mode, or
+// writes failures other than a missing log entry to os.Stderr.
+func check(err error) bool {
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, ErrNotPrinted) {
+		return false
+	}
+
+	if strict.Load() {
+		panic(err)
+	}
+
+	if !errors.Is(err, ErrNoEntry) {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	return false
+}
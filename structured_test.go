@@ -1,10 +1,8 @@
-//go:build structuredlogs
-// +build structuredlogs
-
 package logs_test
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -13,103 +11,89 @@ import (
 	"github.com/rclark/logs"
 )
 
-func Example() {
-	ctx := logs.
-		NewLogger(logs.NewExampleLog).
-		Set(context.Background())
-
-	ctx = logs.AddEntry[logs.ExampleLog](ctx)
-
-	logs.Adjust(ctx, func(e *logs.ExampleLog) {
-		e.Name = "test"
-		e.Count = 42
-		e.Flag = true
-		e.Messages = []string{"hello", "world"}
-	})
-
-	logs.Print[logs.ExampleLog](ctx, logs.WithCurrentTime(time.Time{}))
-	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":42,"flag":true,"messages":["hello","world"]}
-}
-
-func ExampleAdjust() {
+func ExampleFor() {
 	ctx := logs.
 		NewLogger(logs.NewExampleLog).
 		Set(context.Background())
 
-	ctx = logs.AddEntry[logs.ExampleLog](ctx)
+	structured := logs.For[logs.ExampleLog]()
+	ctx = structured.AddEntry(ctx)
 
-	logs.Adjust(ctx, func(e *logs.ExampleLog) {
+	structured.Adjust(ctx, func(e *logs.ExampleLog) {
 		e.Name = "test"
 		e.Count = 42
 		e.Flag = true
 		e.Messages = []string{"hello", "world"}
 	})
 
-	logs.Print[logs.ExampleLog](ctx, logs.WithCurrentTime(time.Time{}))
+	structured.Print(ctx, logs.WithCurrentTime(time.Time{}))
 	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":42,"flag":true,"messages":["hello","world"]}
 }
 
-func ExampleGetEntry() {
+func ExampleFor_getEntry() {
 	ctx := logs.
 		NewLogger(logs.NewExampleLog).
 		Set(context.Background())
 
-	ctx = logs.AddEntry[logs.ExampleLog](ctx)
+	structured := logs.For[logs.ExampleLog]()
+	ctx = structured.AddEntry(ctx)
 
-	logs.Adjust(ctx, func(e *logs.ExampleLog) {
+	structured.Adjust(ctx, func(e *logs.ExampleLog) {
 		e.Name = "test"
 		e.Count = 42
 		e.Flag = true
 		e.Messages = []string{"hello", "world"}
 	})
 
-	e := logs.GetEntry[logs.ExampleLog](ctx)
+	e := structured.GetEntry(ctx)
 	if e == nil {
 		log.Fatal("entry not found")
 	}
 
 	e.Flag = false
 
-	logs.Print[logs.ExampleLog](ctx, logs.WithCurrentTime(time.Time{}))
+	structured.Print(ctx, logs.WithCurrentTime(time.Time{}))
 	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":42,"flag":false,"messages":["hello","world"]}
 }
 
-func Example_logLevels() {
+func ExampleLogger_SetLevel() {
 	// Will only print logs at or above INFO level.
 	printOptions := []logs.PrintOption{
 		logs.WithCurrentTime(time.Time{}),
 		logs.WithLevel(logs.INFO),
 	}
 
+	structured := logs.For[logs.ExampleLog]()
 	newCtx := func() context.Context {
-		return logs.
+		ctx := logs.
 			NewLogger(logs.NewExampleLog).
 			Set(context.Background())
+		return structured.AddEntry(ctx, logs.WithDefaultLevel(logs.DEBUG))
 	}
 
-	ctx := logs.AddEntry[logs.ExampleLog](newCtx(), logs.WithDefaultLevel(logs.DEBUG))
-	logs.Adjust(ctx, func(e *logs.ExampleLog) {
+	ctx := newCtx()
+	structured.Adjust(ctx, func(e *logs.ExampleLog) {
 		e.Messages = []string{"debug"}
 	})
-	logs.Print[logs.ExampleLog](ctx, printOptions...)
+	structured.Print(ctx, printOptions...)
 
-	ctx = logs.AddEntry[logs.ExampleLog](newCtx(), logs.WithDefaultLevel(logs.DEBUG))
-	logs.Info[logs.ExampleLog](ctx)
-	logs.Adjust(ctx, func(e *logs.ExampleLog) {
+	ctx = newCtx()
+	structured.Info(ctx)
+	structured.Adjust(ctx, func(e *logs.ExampleLog) {
 		e.Messages = []string{"info"}
 	})
-	logs.Print[logs.ExampleLog](ctx, printOptions...)
+	structured.Print(ctx, printOptions...)
 
-	ctx = logs.AddEntry[logs.ExampleLog](newCtx(), logs.WithDefaultLevel(logs.DEBUG))
-	logs.Warn[logs.ExampleLog](ctx)
-	logs.Print[logs.ExampleLog](ctx, printOptions...)
+	ctx = newCtx()
+	structured.Warn(ctx)
+	structured.Print(ctx, printOptions...)
 
-	ctx = logs.AddEntry[logs.ExampleLog](newCtx(), logs.WithDefaultLevel(logs.DEBUG))
-	logs.Error[logs.ExampleLog](ctx)
-	logs.Adjust(ctx, func(e *logs.ExampleLog) {
+	ctx = newCtx()
+	structured.SetLevel(ctx, logs.ERROR)
+	structured.Adjust(ctx, func(e *logs.ExampleLog) {
 		e.Messages = []string{"error"}
 	})
-	logs.Print[logs.ExampleLog](ctx, printOptions...)
+	structured.Print(ctx, printOptions...)
 	// Output:
 	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false,"messages":["info"]}
 	// {"@level":"WARN","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false}
@@ -130,8 +114,8 @@ var structuredHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Req
 	})
 })
 
-func ExampleMiddleware() {
-	middleware := logs.Middleware(logs.NewExampleLog, logs.WithTiming(time.Time{}, time.Duration(1234)))
+func ExampleFor_middleware() {
+	middleware := logs.For[logs.ExampleLog]().Middleware(logs.WithTiming(time.Time{}, time.Duration(1234)))
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/path", nil)
@@ -139,3 +123,86 @@ func ExampleMiddleware() {
 	middleware(structuredHandler).ServeHTTP(w, r)
 	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":42,"flag":true,"messages":["hello","world"]}
 }
+
+func ExampleFor_contextLogger() {
+	logger := logs.NewLogger(logs.NewExampleLog).WithBound(func(e *logs.ExampleLog) {
+		e.Name = "bound"
+	})
+
+	middleware := logs.For[logs.ExampleLog]().Middleware(logs.WithTiming(time.Time{}, time.Duration(1234)))
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.For[logs.ExampleLog]().Adjust(r.Context(), func(e *logs.ExampleLog) {
+			e.Count++
+		})
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/path", nil)
+
+	handler.ServeHTTP(w, r.WithContext(logger.Set(r.Context())))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"bound","count":1,"flag":false}
+}
+
+func ExampleLogger_Finalize() {
+	ctx := logs.
+		NewLogger(logs.NewExampleLog).
+		Set(context.Background())
+
+	structured := logs.For[logs.ExampleLog]()
+	ctx = structured.AddEntry(ctx)
+
+	structured.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+	})
+
+	structured.Finalize(ctx, logs.WithCurrentTime(time.Time{}))
+	structured.Finalize(ctx, logs.WithCurrentTime(time.Time{}))
+
+	fmt.Println(structured.Adjust(ctx, func(e *logs.ExampleLog) {
+		e.Name = "changed"
+	}))
+	// Output:
+	// {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"test","count":0,"flag":false}
+	// false
+}
+
+func ExampleLogger_AdjustChanged() {
+	ctx := logs.
+		NewLogger(logs.NewExampleLog).
+		Set(context.Background())
+
+	structured := logs.For[logs.ExampleLog]()
+	ctx = structured.AddEntry(ctx)
+
+	fmt.Println(structured.AdjustChanged(ctx, func(e *logs.ExampleLog) {
+		e.Name = ""
+	}))
+
+	fmt.Println(structured.AdjustChanged(ctx, func(e *logs.ExampleLog) {
+		e.Name = "test"
+	}))
+
+	fmt.Println(structured.AdjustChanged(context.Background(), func(e *logs.ExampleLog) {
+		e.Name = "test"
+	}))
+	// Output:
+	// false true
+	// true true
+	// false false
+}
+
+func ExampleAppendTo() {
+	ctx := logs.
+		NewLogger(logs.NewExampleLog).
+		Set(context.Background())
+
+	structured := logs.For[logs.ExampleLog]()
+	ctx = structured.AddEntry(ctx)
+
+	messages := func(e *logs.ExampleLog) *[]string { return &e.Messages }
+	logs.AppendTo(ctx, messages, "hello")
+	logs.AppendTo(ctx, messages, "world", "again")
+
+	structured.Print(ctx, logs.WithCurrentTime(time.Time{}))
+	// Output: {"@level":"INFO","@time":"0001-01-01T00:00:00Z","name":"","count":0,"flag":false,"messages":["hello","world","again"]}
+}
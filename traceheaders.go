@@ -0,0 +1,221 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TraceData is the data structure for the trace context that the middleware
+// reads from request headers when the [WithTraceHeaders] option is used. It is
+// printed under the "@trace" key of each log entry.
+type TraceData struct {
+	TraceID  string `json:"trace_id"`
+	SpanID   string `json:"span_id"`
+	ParentID string `json:"parent_id,omitempty"`
+	Sampled  *bool  `json:"sampled,omitempty"`
+	State    string `json:"state,omitempty"`
+	Format   string `json:"format"`
+}
+
+// WithTraceHeaders configures the middleware to read the trace context of each
+// request from its headers, and to print it under the "@trace" key of each log
+// entry. W3C "traceparent" and "tracestate" headers are read first, followed by
+// the single "b3" header and the multiple "X-B3-*" headers used by Zipkin, and
+// then the "X-Datadog-*" headers used by Datadog's tracers. This works without
+// an OpenTelemetry SDK. The trace context is available to the downstream
+// handler using [TraceContext].
+func WithTraceHeaders() MiddlewareOption {
+	return func(o *option) {
+		o.traceHeaders = true
+	}
+}
+
+type traceDataKey struct{}
+
+var tdKey = traceDataKey{}
+
+// TraceContext retrieves the trace context that the middleware read from the
+// request's headers when the [WithTraceHeaders] option is used. The function
+// will return nil if no trace context is found in the context.
+func TraceContext(ctx context.Context) *TraceData {
+	td, _ := ctx.Value(tdKey).(*TraceData)
+	return td
+}
+
+// withTraceHeaders reads the trace context from the request's headers, if the
+// middleware is configured to do so.
+func withTraceHeaders(r *http.Request, opt option) *http.Request {
+	if !opt.traceHeaders {
+		return r
+	}
+
+	td, ok := parseTraceparent(r.Header)
+	if !ok {
+		td, ok = parseB3(r.Header)
+	}
+	if !ok {
+		td, ok = parseB3Multi(r.Header)
+	}
+	if !ok {
+		td, ok = parseDatadog(r.Header)
+	}
+	if !ok {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), tdKey, &td))
+}
+
+// parseTraceparent reads the W3C trace context headers.
+func parseTraceparent(h http.Header) (TraceData, bool) {
+	parts := strings.Split(strings.TrimSpace(h.Get("traceparent")), "-")
+	if len(parts) < 4 || !isHex(parts[0], 2) || parts[0] == "ff" {
+		return TraceData{}, false
+	}
+
+	if parts[0] == "00" && len(parts) != 4 {
+		return TraceData{}, false
+	}
+
+	traceID, spanID, flags := parts[1], parts[2], parts[3]
+	if !isID(traceID, 32) || !isID(spanID, 16) || !isHex(flags, 2) {
+		return TraceData{}, false
+	}
+
+	sampled := strings.ContainsAny(flags[1:], "13579bdf")
+
+	return TraceData{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: &sampled,
+		State:   strings.Join(h.Values("tracestate"), ","),
+		Format:  "w3c",
+	}, true
+}
+
+// parseB3 reads the single B3 header, in the form
+// {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}, where the last two parts
+// are optional.
+func parseB3(h http.Header) (TraceData, bool) {
+	parts := strings.Split(strings.TrimSpace(h.Get("b3")), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return TraceData{}, false
+	}
+
+	td := TraceData{TraceID: parts[0], SpanID: parts[1], Format: "b3"}
+	if !isB3TraceID(td.TraceID) || !isID(td.SpanID, 16) {
+		return TraceData{}, false
+	}
+
+	if len(parts) > 2 {
+		sampled, ok := b3Sampled(parts[2])
+		if !ok {
+			return TraceData{}, false
+		}
+		td.Sampled = &sampled
+	}
+
+	if len(parts) > 3 {
+		if !isID(parts[3], 16) {
+			return TraceData{}, false
+		}
+		td.ParentID = parts[3]
+	}
+
+	return td, true
+}
+
+// parseB3Multi reads the multiple "X-B3-*" headers.
+func parseB3Multi(h http.Header) (TraceData, bool) {
+	td := TraceData{
+		TraceID:  strings.ToLower(h.Get("X-B3-TraceId")),
+		SpanID:   strings.ToLower(h.Get("X-B3-SpanId")),
+		ParentID: strings.ToLower(h.Get("X-B3-ParentSpanId")),
+		Format:   "b3",
+	}
+
+	if !isB3TraceID(td.TraceID) || !isID(td.SpanID, 16) {
+		return TraceData{}, false
+	}
+
+	if td.ParentID != "" && !isID(td.ParentID, 16) {
+		td.ParentID = ""
+	}
+
+	if h.Get("X-B3-Flags") == "1" {
+		sampled := true
+		td.Sampled = &sampled
+	} else if sampled, ok := b3Sampled(strings.ToLower(h.Get("X-B3-Sampled"))); ok {
+		td.Sampled = &sampled
+	}
+
+	return td, true
+}
+
+// parseDatadog reads the "X-Datadog-*" headers, which hold decimal 64-bit IDs.
+// The IDs are converted to hexadecimal to match the other formats.
+func parseDatadog(h http.Header) (TraceData, bool) {
+	traceID, err := strconv.ParseUint(h.Get("X-Datadog-Trace-Id"), 10, 64)
+	if err != nil || traceID == 0 {
+		return TraceData{}, false
+	}
+
+	spanID, err := strconv.ParseUint(h.Get("X-Datadog-Parent-Id"), 10, 64)
+	if err != nil || spanID == 0 {
+		return TraceData{}, false
+	}
+
+	td := TraceData{
+		TraceID: fmt.Sprintf("%016x", traceID),
+		SpanID:  fmt.Sprintf("%016x", spanID),
+		Format:  "datadog",
+	}
+
+	if priority, err := strconv.Atoi(h.Get("X-Datadog-Sampling-Priority")); err == nil {
+		sampled := priority > 0
+		td.Sampled = &sampled
+	}
+
+	return td, true
+}
+
+// b3Sampled parses a B3 sampling decision. Debug decisions are sampled.
+func b3Sampled(s string) (bool, bool) {
+	switch s {
+	case "1", "d", "true":
+		return true, true
+	case "0", "false":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// isB3TraceID reports whether s is a valid 64-bit or 128-bit B3 trace ID.
+func isB3TraceID(s string) bool {
+	return isID(s, 16) || isID(s, 32)
+}
+
+// isID reports whether s is a lowercase hexadecimal ID of the given length
+// that is not all zeros.
+func isID(s string, n int) bool {
+	return isHex(s, n) && strings.Trim(s, "0") != ""
+}
+
+// isHex reports whether s is lowercase hexadecimal of the given length.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
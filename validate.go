@@ -0,0 +1,95 @@
+package logs
+
+import "encoding/json"
+
+// Validator checks a log entry before it is printed, such as to enforce that
+// fields like a request ID are set. It returns an error describing what is
+// wrong with the log entry, or nil if the log entry is valid. Use errors.Join
+// to report more than one problem.
+type Validator[T any] func(entry *T) error
+
+// WithValidator registers a validator on the log entry. The validator is
+// called each time the log entry is printed, after any hooks. If it returns an
+// error, the log entry is still printed, but the error's message is listed
+// under the "@validation_errors" field. An error created by errors.Join is
+// listed as one message per joined error. Validators whose type does not
+// match the log entry's type are ignored, and a diagnostic message is written
+// to os.Stderr; use FreeformEntry as the type for freeform log entries.
+func WithValidator[T any](fn Validator[T]) Option {
+	return func(o *option) {
+		o.validators = append(o.validators, fn)
+	}
+}
+
+// Validate registers a validator on the log entries produced by the
+// middleware, as described by [WithValidator].
+func Validate[T any](fn Validator[T]) MiddlewareOption {
+	return MiddlewareOption(WithValidator(fn))
+}
+
+// WithValidators creates a copy of the logger that registers validators on
+// every log entry it creates, as described by [WithValidator].
+func (logger Logger[T]) WithValidators(fns ...Validator[T]) Logger[T] {
+	logger.validators = append(append([]Validator[T]{}, logger.validators...), fns...)
+	return logger
+}
+
+// entryValidators selects the validators that match the log entry's type.
+func entryValidators[T any](o option) []Validator[T] {
+	var validators []Validator[T]
+	for _, v := range o.validators {
+		if validator, ok := v.(Validator[T]); ok {
+			validators = append(validators, validator)
+		} else {
+			mismatched[T]("validator", v)
+		}
+	}
+
+	return validators
+}
+
+// validate runs the log entry's validators, and lists any errors under the
+// "@validation_errors" field of the marshaled log entry.
+func (e *entry[T]) validate(data []byte) []byte {
+	var messages []string
+	for _, validator := range e.validators {
+		if err := validator(e.data); err != nil {
+			messages = append(messages, errorMessages(err)...)
+		}
+	}
+
+	if len(messages) == 0 {
+		return data
+	}
+
+	m, ok := toMap(data)
+	if !ok {
+		return data
+	}
+
+	m["@validation_errors"] = messages
+	annotated, err := json.Marshal(m)
+	if err != nil {
+		return data
+	}
+
+	return annotated
+}
+
+// errorMessages lists the messages of an error, with one message for each
+// error joined by errors.Join.
+func errorMessages(err error) []string {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []string{err.Error()}
+	}
+
+	var messages []string
+	for _, e := range joined.Unwrap() {
+		if e != nil {
+			messages = append(messages, errorMessages(e)...)
+		}
+	}
+
+	return messages
+}